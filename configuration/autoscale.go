@@ -0,0 +1,48 @@
+package configuration
+
+import "time"
+
+/*
+	Autoscale loop configuration
+*/
+type Autoscale struct {
+	// seconds between evaluations of the autoscaled apps
+	IntervalSeconds int
+	// seconds to wait after a Marathon deployment finishes before an app is evaluated again
+	DeploymentSettleSeconds int
+	// seconds for the tasks added by a scale-out to become healthy before it is rolled back
+	VerifySeconds int
+	// seconds an app is left alone after a scale-out was rolled back
+	BackoffSeconds int
+	// seconds after a task starts before its statistics are used, unless the app sets warmupSeconds
+	WarmupSeconds int
+	// URL alerts are posted to as JSON
+	AlertWebhook string
+}
+
+func (a Autoscale) Interval() time.Duration {
+	return seconds(a.IntervalSeconds, 30)
+}
+
+func (a Autoscale) DeploymentSettlePeriod() time.Duration {
+	return seconds(a.DeploymentSettleSeconds, 60)
+}
+
+func (a Autoscale) VerifyPeriod() time.Duration {
+	return seconds(a.VerifySeconds, 300)
+}
+
+func (a Autoscale) BackoffPeriod() time.Duration {
+	return seconds(a.BackoffSeconds, 900)
+}
+
+func (a Autoscale) WarmupPeriod() time.Duration {
+	return seconds(a.WarmupSeconds, 60)
+}
+
+func seconds(value int, defaultValue int) time.Duration {
+	if value <= 0 {
+		value = defaultValue
+	}
+	return time.Duration(value) * time.Second
+}
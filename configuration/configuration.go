@@ -18,6 +18,19 @@ type Configuration struct {
 	Marathon Marathon
 
 	Mesos Mesos
+
+	// Autoscale loop configuration
+	Autoscale Autoscale
+
+	// Timeouts and retries of the requests to Marathon and Mesos
+	HTTP HTTP
+
+	// DC/OS service account, when Marathon and Mesos are behind Admin Router
+	ServiceAccount ServiceAccount
+
+	// Autoscale policies for apps without autoscale labels and defaults for
+	// the apps of Marathon groups
+	Policies []Policy
 }
 
 /*
@@ -40,6 +53,18 @@ func FromFile(filePath string) (Configuration, error) {
 	setValueFromEnv(&conf.Marathon.Endpoint, "MARATHON_ENDPOINT")
 	setValueFromEnv(&conf.Marathon.User, "MARATHON_USER")
 	setValueFromEnv(&conf.Marathon.Password, "MARATHON_PASSWORD")
+	setBoolValueFromEnv(&conf.Marathon.EventStream, "MARATHON_EVENT_STREAM")
+	setValueFromEnv(&conf.Mesos.Endpoint, "MESOS_ENDPOINT")
+	setValueFromEnv(&conf.Mesos.User, "MESOS_USER")
+	setSecretValueFromEnv(&conf.Mesos.Password, "MESOS_PASSWORD")
+	setSecretValueFromEnv(&conf.Mesos.Token, "MESOS_TOKEN")
+	setValueFromEnv(&conf.Mesos.AgentProxy, "MESOS_AGENT_PROXY")
+	setValueFromEnv(&conf.Mesos.Agent.User, "MESOS_AGENT_USER")
+	setSecretValueFromEnv(&conf.Mesos.Agent.Password, "MESOS_AGENT_PASSWORD")
+	setSecretValueFromEnv(&conf.Mesos.Agent.Token, "MESOS_AGENT_TOKEN")
+	setValueFromEnv(&conf.ServiceAccount.UID, "DCOS_SERVICE_ACCOUNT")
+	setValueFromEnv(&conf.ServiceAccount.PrivateKeyFile, "DCOS_PRIVATE_KEY_FILE")
+	setValueFromEnv(&conf.ServiceAccount.LoginEndpoint, "DCOS_LOGIN_ENDPOINT")
 
 	return *conf, err
 }
@@ -52,6 +77,15 @@ func setValueFromEnv(field *string, envVar string) {
 	}
 }
 
+// setSecretValueFromEnv is setValueFromEnv without logging the value
+func setSecretValueFromEnv(field *string, envVar string) {
+	env := os.Getenv(envVar)
+	if len(env) > 0 {
+		log.Printf("Using environment override %s", envVar)
+		*field = env
+	}
+}
+
 func setBoolValueFromEnv(field *bool, envVar string) {
 	env := os.Getenv(envVar)
 	if len(env) > 0 {
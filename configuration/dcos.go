@@ -0,0 +1,20 @@
+package configuration
+
+/*
+	DC/OS service account, logged in through the Admin Router to obtain the
+	ACS token sent to Marathon and Mesos instead of basic auth
+*/
+type ServiceAccount struct {
+	// service account ID
+	UID string
+	// PEM RSA private key of the service account
+	PrivateKeyFile string
+	// cluster URL the login is requested on, e.g. https://master.mesos
+	LoginEndpoint string
+	// HTTPS settings of the login endpoint
+	TLS TLS
+}
+
+func (s ServiceAccount) Enabled() bool {
+	return len(s.UID) > 0
+}
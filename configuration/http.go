@@ -0,0 +1,37 @@
+package configuration
+
+import "time"
+
+/*
+	Timeouts and retries of the requests to Marathon and Mesos
+*/
+type HTTP struct {
+	// seconds before a request times out, 10 when zero
+	TimeoutSeconds int
+	// retries of failed idempotent requests, 3 when zero, none when negative
+	Retries int
+	// milliseconds before the first retry, doubled on every retry, 200 when zero
+	BackoffMillis int
+}
+
+func (h HTTP) Timeout() time.Duration {
+	return seconds(h.TimeoutSeconds, 10)
+}
+
+func (h HTTP) RetryCount() int {
+	switch {
+	case h.Retries < 0:
+		return 0
+	case h.Retries == 0:
+		return 3
+	default:
+		return h.Retries
+	}
+}
+
+func (h HTTP) Backoff() time.Duration {
+	if h.BackoffMillis <= 0 {
+		return 200 * time.Millisecond
+	}
+	return time.Duration(h.BackoffMillis) * time.Millisecond
+}
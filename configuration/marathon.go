@@ -2,6 +2,7 @@ package configuration
 
 import (
 	"strings"
+	"time"
 )
 
 /*
@@ -12,8 +13,19 @@ type Marathon struct {
 	Endpoint string
 	User     string
 	Password string
+	// mirror apps, tasks, groups and deployments from the /v2/events stream
+	// instead of polling them
+	EventStream bool
+	// seconds between full resyncs of the event stream mirror
+	ResyncSeconds int
+	// HTTPS settings of the Marathon endpoints
+	TLS TLS
 }
 
 func (m Marathon) Endpoints() []string {
 	return strings.Split(m.Endpoint, ",")
 }
+
+func (m Marathon) ResyncPeriod() time.Duration {
+	return seconds(m.ResyncSeconds, 300)
+}
@@ -7,8 +7,64 @@ type Mesos struct {
 	Endpoint string
 	User     string
 	Password string
+	// bearer token for the masters, used instead of User and Password when set
+	Token string
+	// credentials for the agents, the master credentials are used when empty
+	Agent Credentials
+	// HTTPS settings of the masters
+	TLS TLS
+	// HTTPS settings of the agents, the master settings are used when empty
+	AgentTLS TLS
+	// scheme of the agent endpoints, http or https, defaults to http
+	AgentScheme string
+	// base URL of a proxy serving the agents under /agent/<id>/, for agents not
+	// reachable from the autoscaler. On DC/OS this is the cluster URL, where
+	// Admin Router proxies the agents, while Endpoint is the cluster URL
+	// followed by /mesos. Agents are reached directly when empty.
+	AgentProxy string
+	// legacy for the /slaves and /monitor/statistics endpoints, or v1 for the operator API
+	API string
+}
+
+/*
+	HTTP credentials, either basic auth or a bearer token
+*/
+type Credentials struct {
+	User     string
+	Password string
+	// bearer token, used instead of User and Password when set
+	Token string
+}
+
+func (c Credentials) Empty() bool {
+	return len(c.User) == 0 && len(c.Password) == 0 && len(c.Token) == 0
 }
 
 func (m Mesos) Endpoints() []string {
 	return strings.Split(m.Endpoint, ",")
 }
+
+func (m Mesos) MasterCredentials() Credentials {
+	return Credentials{User: m.User, Password: m.Password, Token: m.Token}
+}
+
+func (m Mesos) AgentCredentials() Credentials {
+	if m.Agent.Empty() {
+		return m.MasterCredentials()
+	}
+	return m.Agent
+}
+
+func (m Mesos) AgentTLSConfig() TLS {
+	if m.AgentTLS.Empty() {
+		return m.TLS
+	}
+	return m.AgentTLS
+}
+
+func (m Mesos) AgentURL(endpoint string) string {
+	if len(m.AgentScheme) == 0 {
+		return "http://" + endpoint
+	}
+	return m.AgentScheme + "://" + endpoint
+}
@@ -0,0 +1,95 @@
+package configuration
+
+import "path"
+
+/*
+	Autoscale policy declared in the configuration file, for apps whose
+	definitions cannot carry the autoscale labels themselves
+*/
+type Policy struct {
+	// Marathon app ID or glob pattern, e.g. /product/*/service/*
+	AppID string
+	// Marathon group ID, e.g. /product/us-east, instead of AppID for the
+	// defaults of every app in the group and its subgroups
+	GroupID string
+	// autoscale settings keyed the same as the Marathon app labels
+	Labels map[string]string
+}
+
+/*
+	Returns true when the policy applies to the given Marathon app ID
+
+	Parameters:
+		appID: Marathon app ID
+*/
+func (p Policy) Matches(appID string) bool {
+	if p.AppID == appID {
+		return true
+	}
+	matched, err := path.Match(p.AppID, appID)
+	if err != nil {
+		logger.Printf("Invalid policy pattern %s: %s", p.AppID, err)
+		return false
+	}
+	return matched
+}
+
+/*
+	Returns the autoscale labels declared in the configuration file for an app.
+
+	Precedence, highest first:
+		1. a policy whose AppID equals the app ID
+		2. glob policies, in the order they are declared
+	Both override the group policies and the app's own labels override all,
+	see autoscale.policyLabels.
+
+	Parameters:
+		appID: Marathon app ID
+*/
+func (config Configuration) PolicyLabels(appID string) map[string]string {
+	labels := map[string]string{}
+
+	for i := len(config.Policies) - 1; i >= 0; i-- {
+		policy := config.Policies[i]
+		if policy.AppID != appID && policy.Matches(appID) {
+			for key, value := range policy.Labels {
+				labels[key] = value
+			}
+		}
+	}
+
+	for _, policy := range config.Policies {
+		if policy.AppID == appID {
+			for key, value := range policy.Labels {
+				labels[key] = value
+			}
+		}
+	}
+
+	return labels
+}
+
+/*
+	Returns the autoscale labels declared in the configuration file for the
+	groups of an app. Marathon groups carry no labels, so group defaults are
+	declared as policies keyed by GroupID; the policy of a nested group
+	overrides those of its parents.
+
+	Parameters:
+		groupIDs: IDs of the Marathon groups enclosing the app, outermost first
+*/
+func (config Configuration) GroupPolicyLabels(groupIDs []string) map[string]string {
+	labels := map[string]string{}
+
+	for _, groupID := range groupIDs {
+		for _, policy := range config.Policies {
+			if len(policy.GroupID) > 0 && policy.GroupID == groupID {
+				for key, value := range policy.Labels {
+					labels[key] = value
+				}
+			}
+		}
+	}
+
+	return labels
+}
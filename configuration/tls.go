@@ -0,0 +1,59 @@
+package configuration
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+)
+
+/*
+	TLS configuration of the HTTPS connections to a class of endpoints
+*/
+type TLS struct {
+	// PEM bundle of the CAs to verify servers with, the system roots when empty
+	CAFile string
+	// PEM client certificate and key for mutual TLS
+	CertFile string
+	KeyFile  string
+	// name to verify server certificates against instead of the endpoint host
+	ServerName string
+	// skip verification of server certificates, for labs only
+	Insecure bool
+}
+
+func (t TLS) Empty() bool {
+	return t == TLS{}
+}
+
+/*
+	Returns the crypto/tls configuration, loading the CA bundle and client
+	certificate from disk
+*/
+func (t TLS) Config() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.Insecure,
+	}
+
+	if len(t.CAFile) > 0 {
+		pem, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("No certificates found in " + t.CAFile)
+		}
+	}
+
+	if len(t.CertFile) > 0 || len(t.KeyFile) > 0 {
+		certificate, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
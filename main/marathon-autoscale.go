@@ -1,11 +1,27 @@
 package main
 
 import (
+	"flag"
+	"log"
+
 	"github.com/rossmerr/marathon-autoscale/configuration"
 	"github.com/rossmerr/marathon-autoscale/services/autoscale"
 )
 
 func main() {
+	configFile := flag.String("config", "", "path to the JSON configuration file")
+	flag.Parse()
+
 	conf := &configuration.Configuration{}
-	autoscale.Autoscale(conf)
+	if len(*configFile) > 0 {
+		fileConf, err := configuration.FromFile(*configFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		conf = &fileConf
+	}
+
+	if err := autoscale.Autoscale(conf); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,52 @@
+package autoscale
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/rossmerr/marathon-autoscale/services/httpclient"
+)
+
+type alertMessage struct {
+	AppID   string    `json:"appId"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// alert logs the message and posts it to the configured webhook
+func alert(conf *configuration.Configuration, appID string, message string) {
+	logger.Printf("ALERT %s: %s", appID, message)
+
+	if len(conf.Autoscale.AlertWebhook) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(alertMessage{AppID: appID, Message: message, Time: time.Now()})
+	if err != nil {
+		logger.Printf("Error encoding alert: %s", err)
+		return
+	}
+
+	client, err := httpclient.NewWithTimeout(configuration.TLS{}, conf.HTTP)
+	if err != nil {
+		logger.Printf("Error posting alert: %s", err)
+		return
+	}
+
+	req, err := http.NewRequest("POST", conf.Autoscale.AlertWebhook, bytes.NewBuffer(body))
+	if err != nil {
+		logger.Printf("Error posting alert: %s", err)
+		return
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	response, err := client.Do(req)
+	if err != nil {
+		logger.Printf("Error posting alert: %s", err)
+		return
+	}
+	response.Body.Close()
+}
@@ -1,121 +1,246 @@
 package autoscale
 
 import (
-	"strconv"
+	"log"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/rossmerr/marathon-autoscale/configuration"
 	"github.com/rossmerr/marathon-autoscale/services/marathon"
 	"github.com/rossmerr/marathon-autoscale/services/mesos"
+	"github.com/rossmerr/marathon-autoscale/services/metrics"
 )
 
+var logger = log.New(os.Stdout, "", log.Ldate|log.Ltime|log.Lshortfile)
+
 type application struct {
-	AppID               string
+	AppID string
+	// cpu and mem trigger thresholds, negative when the policy only sets a custom metric
 	MaxMemPercent       int
 	MaxCPUTime          int
 	MaxInstances        int
 	TriggerMode         string
 	AutoscaleMultiplier float64
-	Statistics          []mesos.Resource
+	// health trigger threshold, zero when the app does not scale on health
+	MaxUnhealthyPercent int
+	// CPU throttling trigger threshold, zero when the app does not scale on throttling
+	MaxThrottlePercent int
+	// network trigger thresholds per task, zero when the app does not scale on them
+	MaxNetRxMbps float64
+	MaxNetTxMbps float64
+	MaxNetRxPps  float64
+	MaxNetTxPps  float64
+	// custom metric scraped from the tasks, its aggregation across tasks (avg,
+	// sum or max) and trigger threshold
+	Metric            metrics.Source
+	MetricAggregation string
+	MaxMetricValue    float64
+	// last aggregated value of the custom metric and the number of tasks it was scraped from
+	MetricValue float64
+	MetricTasks int
+	// grace period after a task starts before its statistics are used
+	Warmup     time.Duration
+	Statistics []mesos.Resource
+	// tasks left out of the last statistics for being unhealthy, staging or warming up
+	ExcludedTasks int
+	// ready tasks no container statistics were found for in the last step
+	MissingStatistics []string
+	// a Marathon deployment affecting the app is in progress
+	Deploying bool
+	// evaluation is held off until the last deployment has settled
+	SettledAt time.Time
+	// scale-out waiting for its new tasks to become healthy
+	Verifying *verification
+	// evaluation is held off after a failed scale-out was rolled back
+	BackoffUntil time.Time
+	// most recent scaling decisions, oldest first
+	Decisions []decision
+}
+
+// withState carries the runtime state of the app over from the previous step
+// onto its current policy, so policy changes apply from the next step
+func (a application) withState(previous application) application {
+	a.Statistics = previous.Statistics
+	a.Deploying = previous.Deploying
+	a.SettledAt = previous.SettledAt
+	a.Verifying = previous.Verifying
+	a.BackoffUntil = previous.BackoffUntil
+	a.Decisions = previous.Decisions
+	return a
+}
+
+// Autoscaler evaluates the autoscaled Marathon apps against their policies
+type Autoscaler struct {
+	conf     *configuration.Configuration
+	marathon MarathonClient
+	mesos    MesosClient
+	table    map[string]application
 }
 
+// New returns an autoscaler using the given clients
+func New(conf *configuration.Configuration, marathonClient MarathonClient, mesosClient MesosClient) *Autoscaler {
+	return &Autoscaler{
+		conf:     conf,
+		marathon: marathonClient,
+		mesos:    mesosClient,
+		table:    make(map[string]application),
+	}
+}
+
+// Autoscale runs the autoscaler against the configured Marathon and Mesos endpoints
 func Autoscale(conf *configuration.Configuration) error {
+	var mesosClient MesosClient = mesos.NewClient(conf)
+	if conf.Mesos.API == "v1" {
+		mesosClient = mesos.NewOperatorClient(conf)
+	}
 
-	table := make(map[string]application)
+	return New(conf, marathon.NewClient(conf), mesosClient).Run()
+}
 
+// Run evaluates the apps every interval. A failed step is logged and retried
+// at the next interval, so Run only returns when the process exits.
+func (a *Autoscaler) Run() error {
 	for {
+		if err := a.Step(time.Now()); err != nil {
+			logger.Printf("Autoscaling step failed: %v", err)
+		}
 
-		resources := make([]mesos.Resource, 0)
+		time.Sleep(a.conf.Autoscale.Interval())
+	}
+}
 
-		apps, err := marathon.FetchApps(conf)
-		if err != nil {
-			panic(err)
-		}
+// Step fetches the current state of the cluster and evaluates every app once
+func (a *Autoscaler) Step(now time.Time) error {
+	conf := a.conf
+	table := a.table
+	resources := make([]mesos.Resource, 0)
 
-		tasks, err := marathon.FetchTasks(conf)
-		if err != nil {
-			panic(err)
-		}
+	apps, err := a.marathon.FetchApps()
+	if err != nil {
+		return err
+	}
 
-		agents, err := mesos.FetchAgents(conf)
-		if err != nil {
-			panic(err)
-		}
+	tasks, err := a.marathon.FetchTasks()
+	if err != nil {
+		return err
+	}
 
-		for _, agent := range agents {
-			statistics, err := agent.FetchAgentStatistics()
-			if err != nil {
-				return err
-			}
+	groups, err := a.marathon.FetchGroups()
+	if err != nil {
+		return err
+	}
+
+	appGroups := groups.AppGroups()
+
+	deployments, err := a.marathon.FetchDeployments()
+	if err != nil {
+		return err
+	}
+
+	deploying := deployingApps(deployments)
+
+	agents, err := a.mesos.FetchAgents()
+	if err != nil {
+		return err
+	}
+
+	// without the Mesos tasks statistics are matched by executor ID and source only
+	mesosTasks, err := a.mesos.FetchTasks()
+	if err != nil {
+		logger.Printf("Fetching Mesos tasks failed: %v", err)
+	}
 
-			resources = append(resources, statistics...)
+	tasksByApp := groupTasks(tasks)
+
+	autoscaled := map[string]application{}
+	for _, app := range apps {
+		if application, ok := newApplication(conf, policyLabels(conf, appGroups[app.ID], app), app); ok {
+			autoscaled[app.ID] = application
 		}
+	}
 
-		for _, app := range apps {
+	for _, agent := range hostingAgents(agents, autoscaled, tasksByApp, mesosTasks) {
+		// the tasks of an unreachable or draining agent are reported without statistics
+		statistics, err := a.mesos.FetchAgentStatistics(agent)
+		if err != nil {
+			logger.Printf("Fetching statistics of agent %s failed: %v", agent.ID, err)
+			continue
+		}
 
-			var maxMemPercent, maxCPUTime, maxInstances int
-			var triggerMode string
-			var autoscaleMultiplier float64
-			var ok bool
+		resources = append(resources, statistics...)
+	}
 
-			if maxMemPercent, err = strconv.Atoi(app.Labels["maxMemPercent"]); err != nil {
-				continue
-			}
+	statisticsByTask := newStatisticsIndex(resources)
 
-			if maxCPUTime, err = strconv.Atoi(app.Labels["maxCPUTime"]); err != nil {
-				continue
-			}
+	for appID, application := range autoscaled {
+		app := apps[appID]
+		appTasks := tasksByApp[app.ID]
 
-			if maxInstances, err = strconv.Atoi(app.Labels["maxInstances"]); err != nil {
-				continue
-			}
+		metricTasks := readyTasks(app, appTasks, now, application.Warmup)
 
-			if triggerMode, ok = app.Labels["triggerMode"]; !ok {
-				triggerMode = "both"
-			}
+		statistics, missing := statisticsByTask.matchStatistics(metricTasks, mesosTasks)
 
-			if autoscaleMultiplier, err = strconv.ParseFloat(app.Labels["autoscaleMultiplier"], 64); err != nil {
-				autoscaleMultiplier = 1.5
-			}
+		if previous, ok := table[app.ID]; ok {
+			application = application.withState(previous)
+		}
 
-			appTasks := findAppTasks(tasks, func(appID string) bool {
-				return app.ID == appID
-			})
+		application.ExcludedTasks = len(appTasks) - len(metricTasks)
+		application.MissingStatistics = application.MissingStatistics[:0]
+		for _, task := range missing {
+			application.MissingStatistics = append(application.MissingStatistics, task.ID)
+		}
+		if len(missing) > 0 {
+			logger.Printf("No statistics found for %d tasks of %s: %s", len(missing), app.ID, strings.Join(application.MissingStatistics, ", "))
+		}
 
-			statistics := filterStatistics(resources, appTasks, func(executorID string) bool {
-				for _, task := range appTasks {
-					if task.ID == executorID {
-						return true
-					}
-				}
-				return false
-			})
+		application.settle(app, deployments)
 
-			application := application{AppID: app.ID, MaxMemPercent: maxMemPercent, MaxCPUTime: maxCPUTime,
-				MaxInstances: maxInstances, TriggerMode: triggerMode, AutoscaleMultiplier: autoscaleMultiplier}
+		if application.Verifying != nil {
+			application = a.verify(app, appTasks, application, now)
+		}
 
-			if app1, ok := table[app.ID]; ok {
-				application = app1
-			}
+		if deploying[app.ID] {
+			application.Deploying = true
+			application.Statistics = nil
+			table[app.ID] = application
+			continue
+		}
 
-			application.Statistics = append(application.Statistics, statistics...)
+		if application.Deploying {
+			application.Deploying = false
+			application.SettledAt = now.Add(conf.Autoscale.DeploymentSettlePeriod())
+		}
 
+		if now.Before(application.SettledAt) {
 			table[app.ID] = application
+			continue
 		}
 
-		// remove old not running apps
-		for id := range table {
-			if _, ok := apps[id]; !ok {
-				delete(table, id)
-				break
-			}
+		if application.Verifying != nil || now.Before(application.BackoffUntil) {
+			application.Statistics = nil
+			table[app.ID] = application
+			continue
 		}
 
-		// for _, app := range table {
-		// 	for stats := app.Statistics {
-		// 		stats..CPUsLimit
-		// 	}
-		// }
+		application.Statistics = append(application.Statistics, statistics...)
+
+		if application.Metric.Enabled() {
+			application.MetricValue, application.MetricTasks = a.scrapeMetric(application, metricTasks)
+		}
+
+		table[app.ID] = a.evaluate(app, appTasks, agents, application, now)
 	}
+
+	// remove old not running apps
+	for id := range table {
+		if _, ok := apps[id]; !ok {
+			delete(table, id)
+			break
+		}
+	}
+
+	return nil
 }
 
 // func filterTasks(s []mesos.Resource, fn func(executorID string) bool) []mesos.Resource {
@@ -128,22 +253,65 @@ func Autoscale(conf *configuration.Configuration) error {
 // 	return p
 // }
 
-func findAppTasks(s map[string]marathon.Task, fn func(marathonApp string) bool) []marathon.Task {
-	p := []marathon.Task{}
-	for _, v := range s {
-		if fn(v.AppID) {
-			p = append(p, v)
+// deployingApps returns the IDs of the apps affected by in-flight deployments
+func deployingApps(deployments map[string]marathon.Deployment) map[string]bool {
+	apps := map[string]bool{}
+	for _, deployment := range deployments {
+		for _, appID := range deployment.AffectedApps {
+			apps[appID] = true
+		}
+	}
+	return apps
+}
+
+// groupTasks returns the tasks by app ID
+func groupTasks(tasks map[string]marathon.Task) map[string][]marathon.Task {
+	p := map[string][]marathon.Task{}
+	for _, v := range tasks {
+		p[v.AppID] = append(p[v.AppID], v)
+	}
+	return p
+}
+
+// hostingAgents returns the agents running tasks of the autoscaled apps, the
+// only ones whose statistics are needed. Agents are found by the agent ID
+// Marathon or Mesos reports for the task, or else by the task's host.
+func hostingAgents(agents map[string]mesos.Slave, autoscaled map[string]application, tasksByApp map[string][]marathon.Task, mesosTasks map[string]mesos.Task) map[string]mesos.Slave {
+	agentIDs := map[string]bool{}
+	hosts := map[string]bool{}
+
+	for appID := range autoscaled {
+		for _, task := range tasksByApp[appID] {
+			switch {
+			case len(task.SlaveID) > 0:
+				agentIDs[task.SlaveID] = true
+			case len(mesosTasks[task.ID].AgentID) > 0:
+				agentIDs[mesosTasks[task.ID].AgentID] = true
+			case len(task.Host) > 0:
+				hosts[task.Host] = true
+			}
+		}
+	}
+
+	p := map[string]mesos.Slave{}
+	for id, agent := range agents {
+		if agentIDs[id] || hosts[agent.Hostname] {
+			p[id] = agent
 		}
 	}
 	return p
 }
 
-func filterStatistics(s []mesos.Resource, m []marathon.Task, fn func(executorID string) bool) []mesos.Resource {
-	p := []mesos.Resource{}
+// readyTasks returns the tasks whose statistics can be trusted: started, past
+// their warm-up grace period and passing their health checks
+func readyTasks(app marathon.App, s []marathon.Task, now time.Time, warmup time.Duration) []marathon.Task {
+	p := []marathon.Task{}
 	for _, v := range s {
-		if fn(v.ExecutorID) {
-			p = append(p, v)
+		startedAt, ok := v.Started()
+		if !ok || now.Sub(startedAt) < warmup || !v.Healthy(app) {
+			continue
 		}
+		p = append(p, v)
 	}
 	return p
 }
@@ -9,8 +9,12 @@ import (
 	"testing"
 
 	"strings"
+	"time"
 
 	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/rossmerr/marathon-autoscale/services/marathon"
+	"github.com/rossmerr/marathon-autoscale/services/mesos"
+	"github.com/stretchr/testify/assert"
 )
 
 const appsJSON = `{
@@ -52,6 +56,14 @@ const appsJSON = `{
     ]
 }`
 
+const groupsJSON = `{
+    "id": "/",
+    "apps": [],
+    "groups": []
+}`
+
+const deploymentsJSON = `[]`
+
 const tasksJSON = `{
     "tasks": [
         {
@@ -221,6 +233,12 @@ func TestAutosacle(t *testing.T) {
 		if r.RequestURI == "/v2/apps" {
 			fmt.Fprintln(w, appsJSON)
 		}
+		if r.RequestURI == "/v2/groups" {
+			fmt.Fprintln(w, groupsJSON)
+		}
+		if r.RequestURI == "/v2/deployments" {
+			fmt.Fprintln(w, deploymentsJSON)
+		}
 		if r.RequestURI == "/v2/tasks" {
 			fmt.Fprintln(w, tasksJSON)
 		}
@@ -249,6 +267,31 @@ func TestAutosacle(t *testing.T) {
 	conf.Marathon.Endpoint = ts.URL
 	conf.Mesos.Endpoint = ts.URL
 
-	Autoscale(conf)
-	fmt.Printf("test")
+	autoscaler := New(conf, marathon.NewClient(conf), mesos.NewClient(conf))
+
+	assert.Nil(t, autoscaler.Step(time.Now()))
+
+	application, ok := autoscaler.table["/product/us-east/service/myapp"]
+	assert.True(t, ok)
+	assert.Equal(t, 1, application.MaxInstances)
+	assert.Equal(t, []string{"bridged-webapp.eb76c51f-4b4a-11e4-ae49-56847afe9799"}, application.MissingStatistics)
+	assert.Empty(t, application.Decisions)
+}
+
+func TestReadyTasks(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2014-10-03T23:00:00Z")
+	app := marathon.App{ID: "/myapp", HealthChecks: []marathon.HealthCheck{{Protocol: "HTTP"}}}
+	alive := []marathon.HealthCheckResult{{Alive: true}}
+	tasks := []marathon.Task{
+		{ID: "healthy", StartedAt: "2014-10-03T22:50:00Z", HealthCheckResults: alive},
+		{ID: "staging", StagedAt: "2014-10-03T22:59:00Z"},
+		{ID: "warming-up", StartedAt: "2014-10-03T22:59:30Z", HealthCheckResults: alive},
+		{ID: "unhealthy", StartedAt: "2014-10-03T22:50:00Z", HealthCheckResults: []marathon.HealthCheckResult{{Alive: false}}},
+		{ID: "no-results", StartedAt: "2014-10-03T22:50:00Z"},
+	}
+
+	ready := readyTasks(app, tasks, now, time.Minute)
+
+	assert.Len(t, ready, 1)
+	assert.Equal(t, "healthy", ready[0].ID)
 }
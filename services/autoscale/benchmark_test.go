@@ -0,0 +1,118 @@
+package autoscale
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/rossmerr/marathon-autoscale/services/marathon"
+	"github.com/rossmerr/marathon-autoscale/services/mesos"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	syntheticApps        = 1000
+	syntheticTasksPerApp = 10
+	syntheticAgents      = 1000
+)
+
+// syntheticCluster returns fakes of a cluster of 10k autoscaled tasks spread
+// over 1k agents, half of them matched by container ID and half by task ID.
+// Another 1k agents run no autoscaled tasks.
+func syntheticCluster() (*fakeMarathon, *fakeMesos) {
+	fm := newFakeMarathon()
+	fs := newFakeMesos()
+
+	for i := 0; i < 2*syntheticAgents; i++ {
+		agentID := "S" + strconv.Itoa(i)
+		fs.agents[agentID] = mesos.Slave{ID: agentID, Active: true,
+			UnReservedResources: mesos.SlaveResources{CPUS: 64, Mem: 262144}}
+	}
+
+	n := 0
+	for i := 0; i < syntheticApps; i++ {
+		appID := "/app-" + strconv.Itoa(i)
+		fm.apps[appID] = marathon.App{ID: appID, Instances: syntheticTasksPerApp, CPUs: 0.5, Mem: 128,
+			Labels: map[string]string{"maxCPUTime": "90", "maxMemPercent": "90", "maxInstances": "20"}}
+
+		for j := 0; j < syntheticTasksPerApp; j++ {
+			taskID := "app-" + strconv.Itoa(i) + "." + strconv.Itoa(j)
+			agentID := "S" + strconv.Itoa(n%syntheticAgents)
+			fm.tasks[taskID] = marathon.Task{AppID: appID, ID: taskID, StartedAt: "2014-10-03T22:00:00Z"}
+
+			resource := sample(taskID, 100, 1, 100)
+			if n%2 == 0 {
+				resource.ExecutorID = "executor-" + taskID
+				resource.ContainerID = "container-" + taskID
+				fs.tasks[taskID] = mesos.Task{ID: taskID, AgentID: agentID, ContainerID: resource.ContainerID}
+			} else {
+				task := fm.tasks[taskID]
+				task.SlaveID = agentID
+				fm.tasks[taskID] = task
+			}
+			fs.statistics[agentID] = append(fs.statistics[agentID], resource)
+			n++
+		}
+	}
+
+	return fm, fs
+}
+
+func BenchmarkStep(b *testing.B) {
+	fm, fs := syntheticCluster()
+	autoscaler := New(&configuration.Configuration{}, fm, fs)
+	start, _ := time.Parse(time.RFC3339, "2014-10-03T23:00:00Z")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := autoscaler.Step(start.Add(time.Duration(i) * time.Minute)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMatchStatistics(b *testing.B) {
+	fm, fs := syntheticCluster()
+	tasksByApp := groupTasks(fm.tasks)
+	resources := []mesos.Resource{}
+	for _, statistics := range fs.statistics {
+		resources = append(resources, statistics...)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index := newStatisticsIndex(resources)
+		for _, tasks := range tasksByApp {
+			if _, missing := index.matchStatistics(tasks, fs.tasks); len(missing) > 0 {
+				b.Fatalf("%d tasks without statistics", len(missing))
+			}
+		}
+	}
+}
+
+// TestStepLargeCluster checks a step over 10k tasks fetches the statistics
+// of each hosting agent once, leaves the idle agents alone and matches every task
+func TestStepLargeCluster(t *testing.T) {
+	if testing.Short() {
+		t.Skip("large synthetic cluster")
+	}
+
+	fm, fs := syntheticCluster()
+	autoscaler := New(&configuration.Configuration{}, fm, fs)
+	start, _ := time.Parse(time.RFC3339, "2014-10-03T23:00:00Z")
+
+	if err := autoscaler.Step(start); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, fs.scraped, syntheticAgents)
+	for agentID, fetches := range fs.scraped {
+		assert.Equal(t, 1, fetches, agentID)
+	}
+
+	assert.Len(t, autoscaler.table, syntheticApps)
+	for appID, application := range autoscaler.table {
+		assert.Empty(t, application.MissingStatistics, appID)
+	}
+}
@@ -0,0 +1,52 @@
+package autoscale
+
+import (
+	"math"
+
+	"github.com/rossmerr/marathon-autoscale/services/marathon"
+	"github.com/rossmerr/marathon-autoscale/services/mesos"
+)
+
+// placeableInstances returns how many more instances of the app fit in the
+// free resources of the active agents. Placement constraints are not taken
+// into account, so the result is an upper bound.
+func placeableInstances(app marathon.App, agents map[string]mesos.Slave) int {
+	if app.CPUs <= 0 && app.Mem <= 0 && app.Disk <= 0 && app.GPUs <= 0 {
+		return math.MaxInt32
+	}
+
+	instances := 0
+	for _, agent := range agents {
+		if !agent.Active {
+			continue
+		}
+
+		free := agent.Free()
+		fits := math.MaxInt32
+		fits = fitsIn(fits, float64(free.CPUS), app.CPUs)
+		fits = fitsIn(fits, float64(free.Mem), app.Mem)
+		fits = fitsIn(fits, float64(free.Disk), app.Disk)
+		fits = fitsIn(fits, float64(free.GPUS), app.GPUs)
+
+		instances += fits
+	}
+
+	return instances
+}
+
+// fitsIn lowers fits to the number of times required fits in free
+func fitsIn(fits int, free float64, required float64) int {
+	if required <= 0 {
+		return fits
+	}
+
+	n := 0
+	if free > 0 {
+		n = int(math.Floor(free / required))
+	}
+
+	if n < fits {
+		return n
+	}
+	return fits
+}
@@ -0,0 +1,36 @@
+package autoscale
+
+import (
+	"testing"
+
+	"github.com/rossmerr/marathon-autoscale/services/marathon"
+	"github.com/rossmerr/marathon-autoscale/services/mesos"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaceableInstances(t *testing.T) {
+	agents := map[string]mesos.Slave{
+		"S1": {
+			Active:              true,
+			UnReservedResources: mesos.SlaveResources{CPUS: 4, Mem: 4096, Disk: 10000},
+			UsedResources:       mesos.SlaveResources{CPUS: 2.5, Mem: 1024},
+		},
+		"S2": {
+			Active:              true,
+			UnReservedResources: mesos.SlaveResources{CPUS: 4, Mem: 2048, Disk: 10000},
+			UsedResources:       mesos.SlaveResources{CPUS: 1, Mem: 1536},
+		},
+		"S3": {
+			Active:              false,
+			UnReservedResources: mesos.SlaveResources{CPUS: 16, Mem: 65536, Disk: 10000},
+		},
+	}
+
+	app := marathon.App{ID: "/myapp", CPUs: 0.5, Mem: 512}
+
+	// S1 fits 3 by cpu and 6 by mem, S2 fits 6 by cpu and 1 by mem
+	assert.Equal(t, 4, placeableInstances(app, agents))
+
+	app.GPUs = 1
+	assert.Equal(t, 0, placeableInstances(app, agents))
+}
@@ -0,0 +1,25 @@
+package autoscale
+
+import (
+	"github.com/rossmerr/marathon-autoscale/services/marathon"
+	"github.com/rossmerr/marathon-autoscale/services/mesos"
+)
+
+// MarathonClient is the Marathon API the autoscaler depends on,
+// marathon.Client being the HTTP implementation
+type MarathonClient interface {
+	FetchApps() (map[string]marathon.App, error)
+	FetchTasks() (map[string]marathon.Task, error)
+	FetchGroups() (marathon.Group, error)
+	FetchDeployments() (map[string]marathon.Deployment, error)
+	ScaleApp(app marathon.App, instances int) (string, error)
+	ForceScaleApp(app marathon.App, instances int) (string, error)
+}
+
+// MesosClient is the Mesos API the autoscaler depends on,
+// mesos.Client being the HTTP implementation
+type MesosClient interface {
+	FetchAgents() (map[string]mesos.Slave, error)
+	FetchAgentStatistics(agent mesos.Slave) ([]mesos.Resource, error)
+	FetchTasks() (map[string]mesos.Task, error)
+}
@@ -0,0 +1,304 @@
+package autoscale
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/rossmerr/marathon-autoscale/services/marathon"
+	"github.com/rossmerr/marathon-autoscale/services/mesos"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMarathon is an in-memory MarathonClient recording scale requests
+type fakeMarathon struct {
+	apps        map[string]marathon.App
+	tasks       map[string]marathon.Task
+	groups      marathon.Group
+	deployments map[string]marathon.Deployment
+	scaled      map[string]int
+	scaleErr    error
+	// apps locked by a deployment, only scaled when forced
+	locked map[string]bool
+	forced map[string]int
+}
+
+func newFakeMarathon() *fakeMarathon {
+	return &fakeMarathon{
+		apps:        map[string]marathon.App{},
+		tasks:       map[string]marathon.Task{},
+		groups:      marathon.Group{ID: "/"},
+		deployments: map[string]marathon.Deployment{},
+		scaled:      map[string]int{},
+		locked:      map[string]bool{},
+		forced:      map[string]int{},
+	}
+}
+
+func (f *fakeMarathon) FetchApps() (map[string]marathon.App, error) { return f.apps, nil }
+
+func (f *fakeMarathon) FetchTasks() (map[string]marathon.Task, error) { return f.tasks, nil }
+
+func (f *fakeMarathon) FetchGroups() (marathon.Group, error) { return f.groups, nil }
+
+func (f *fakeMarathon) FetchDeployments() (map[string]marathon.Deployment, error) {
+	return f.deployments, nil
+}
+
+func (f *fakeMarathon) ScaleApp(app marathon.App, instances int) (string, error) {
+	if f.scaleErr != nil {
+		return "", f.scaleErr
+	}
+	if f.locked[app.ID] {
+		return "", &marathon.ScaleError{AppID: app.ID, StatusCode: http.StatusConflict, Deployments: []string{"deployment-" + app.ID}}
+	}
+	f.scaled[app.ID] = instances
+	return "deployment-" + app.ID, nil
+}
+
+func (f *fakeMarathon) ForceScaleApp(app marathon.App, instances int) (string, error) {
+	f.forced[app.ID]++
+	f.scaled[app.ID] = instances
+	return "forced-deployment-" + app.ID, nil
+}
+
+// fakeMesos is an in-memory MesosClient serving statistics by agent ID
+type fakeMesos struct {
+	agents     map[string]mesos.Slave
+	statistics map[string][]mesos.Resource
+	tasks      map[string]mesos.Task
+	scraped    map[string]int
+	// agents failing to serve statistics
+	failing map[string]error
+}
+
+func newFakeMesos() *fakeMesos {
+	return &fakeMesos{agents: map[string]mesos.Slave{}, statistics: map[string][]mesos.Resource{}, tasks: map[string]mesos.Task{}, scraped: map[string]int{}, failing: map[string]error{}}
+}
+
+func (f *fakeMesos) FetchTasks() (map[string]mesos.Task, error) { return f.tasks, nil }
+
+func (f *fakeMesos) FetchAgents() (map[string]mesos.Slave, error) { return f.agents, nil }
+
+func (f *fakeMesos) FetchAgentStatistics(agent mesos.Slave) ([]mesos.Resource, error) {
+	f.scraped[agent.ID]++
+	if err := f.failing[agent.ID]; err != nil {
+		return nil, err
+	}
+	return f.statistics[agent.ID], nil
+}
+
+func TestStepScalesOut(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2014-10-03T23:00:00Z")
+
+	fm := newFakeMarathon()
+	fm.apps["/myapp"] = marathon.App{ID: "/myapp", Instances: 2, CPUs: 0.5, Mem: 128,
+		Labels: map[string]string{"maxCPUTime": "50", "maxMemPercent": "50", "maxInstances": "10", "autoscaleMultiplier": "2"}}
+	fm.tasks["task-1"] = marathon.Task{AppID: "/myapp", ID: "task-1", SlaveID: "S1", StartedAt: "2014-10-03T22:00:00Z"}
+	fm.tasks["task-2"] = marathon.Task{AppID: "/myapp", ID: "task-2", SlaveID: "S1", StartedAt: "2014-10-03T22:00:00Z"}
+
+	fs := newFakeMesos()
+	fs.agents["S1"] = mesos.Slave{ID: "S1", Active: true,
+		UnReservedResources: mesos.SlaveResources{CPUS: 8, Mem: 8192}}
+
+	autoscaler := New(&configuration.Configuration{}, fm, fs)
+
+	fs.statistics["S1"] = []mesos.Resource{sample("task-1", 100, 10, 600), sample("task-2", 100, 10, 600)}
+	assert.Nil(t, autoscaler.Step(start))
+	assert.Empty(t, fm.scaled)
+
+	fs.statistics["S1"] = []mesos.Resource{sample("task-1", 110, 14, 600), sample("task-2", 110, 14, 600)}
+	assert.Nil(t, autoscaler.Step(start.Add(30*time.Second)))
+
+	assert.Equal(t, 4, fm.scaled["/myapp"])
+	decisions := autoscaler.table["/myapp"].Decisions
+	assert.Len(t, decisions, 1)
+	assert.Equal(t, "deployment-/myapp", decisions[0].DeploymentID)
+	assert.Equal(t, 2, decisions[0].Usage.Tasks)
+}
+
+func TestStepScalesOutOnHealthOnlyPolicy(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2014-10-03T23:00:00Z")
+
+	fm := newFakeMarathon()
+	fm.apps["/myapp"] = marathon.App{ID: "/myapp", Instances: 2, HealthChecks: []marathon.HealthCheck{{Protocol: "HTTP"}},
+		Labels: map[string]string{"maxUnhealthyPercent": "20", "maxInstances": "10"}}
+	fm.tasks["task-1"] = marathon.Task{AppID: "/myapp", ID: "task-1", SlaveID: "S1", StartedAt: "2014-10-03T22:00:00Z",
+		HealthCheckResults: []marathon.HealthCheckResult{{Alive: true}}}
+	fm.tasks["task-2"] = marathon.Task{AppID: "/myapp", ID: "task-2", SlaveID: "S1", StartedAt: "2014-10-03T22:00:00Z",
+		HealthCheckResults: []marathon.HealthCheckResult{{Alive: false, ConsecutiveFailures: 2}}}
+
+	fs := newFakeMesos()
+	fs.agents["S1"] = mesos.Slave{ID: "S1", Active: true}
+
+	autoscaler := New(&configuration.Configuration{}, fm, fs)
+
+	assert.Nil(t, autoscaler.Step(start))
+
+	assert.Equal(t, 3, fm.scaled["/myapp"])
+	decisions := autoscaler.table["/myapp"].Decisions
+	assert.Len(t, decisions, 1)
+	assert.Equal(t, 50.0, decisions[0].Usage.UnhealthyPercent)
+}
+
+func TestStepRetriesFailedRollback(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2014-10-03T23:00:00Z")
+
+	fm := newFakeMarathon()
+	fm.apps["/myapp"] = marathon.App{ID: "/myapp", Instances: 2, HealthChecks: []marathon.HealthCheck{{Protocol: "HTTP"}},
+		Labels: map[string]string{"maxUnhealthyPercent": "20", "maxInstances": "10"}}
+	fm.tasks["task-1"] = marathon.Task{AppID: "/myapp", ID: "task-1", SlaveID: "S1", StartedAt: "2014-10-03T22:00:00Z",
+		HealthCheckResults: []marathon.HealthCheckResult{{Alive: true}}}
+	fm.tasks["task-2"] = marathon.Task{AppID: "/myapp", ID: "task-2", SlaveID: "S1", StartedAt: "2014-10-03T22:00:00Z",
+		HealthCheckResults: []marathon.HealthCheckResult{{Alive: false, ConsecutiveFailures: 2}}}
+
+	fs := newFakeMesos()
+	fs.agents["S1"] = mesos.Slave{ID: "S1", Active: true}
+
+	autoscaler := New(&configuration.Configuration{}, fm, fs)
+
+	assert.Nil(t, autoscaler.Step(start))
+	assert.Equal(t, 3, fm.scaled["/myapp"])
+
+	// the new task never becomes healthy and Marathon rejects the rollback
+	app := fm.apps["/myapp"]
+	app.Instances = 3
+	fm.apps["/myapp"] = app
+	fm.scaleErr = errors.New("Marathon unavailable")
+
+	now := start.Add(10 * time.Minute)
+	assert.Nil(t, autoscaler.Step(now))
+
+	application := autoscaler.table["/myapp"]
+	assert.True(t, application.Verifying.RollingBack)
+	assert.Len(t, application.Decisions, 2)
+	assert.Equal(t, fm.scaleErr, application.Decisions[1].Err)
+
+	// the rollback is retried, past the backoff, instead of scaling out again
+	now = now.Add(time.Hour)
+	assert.Nil(t, autoscaler.Step(now))
+
+	application = autoscaler.table["/myapp"]
+	assert.True(t, application.Verifying.RollingBack)
+	assert.Len(t, application.Decisions, 3)
+	assert.Equal(t, 2, application.Decisions[2].To)
+	assert.Equal(t, 3, fm.scaled["/myapp"])
+
+	fm.scaleErr = nil
+	assert.Nil(t, autoscaler.Step(now.Add(30*time.Second)))
+
+	application = autoscaler.table["/myapp"]
+	assert.Nil(t, application.Verifying)
+	assert.Nil(t, application.Decisions[3].Err)
+	assert.Equal(t, 2, fm.scaled["/myapp"])
+}
+
+func TestStepSkipsDeployingApps(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2014-10-03T23:00:00Z")
+
+	fm := newFakeMarathon()
+	fm.apps["/myapp"] = marathon.App{ID: "/myapp", Instances: 2,
+		Labels: map[string]string{"maxCPUTime": "50", "maxMemPercent": "50", "maxInstances": "10"}}
+	fm.tasks["task-1"] = marathon.Task{AppID: "/myapp", ID: "task-1", SlaveID: "S1", StartedAt: "2014-10-03T22:00:00Z"}
+	fm.deployments["d1"] = marathon.Deployment{ID: "d1", AffectedApps: []string{"/myapp"}}
+
+	fs := newFakeMesos()
+	fs.agents["S1"] = mesos.Slave{ID: "S1", Active: true}
+	fs.statistics["S1"] = []mesos.Resource{sample("task-1", 100, 10, 900)}
+
+	autoscaler := New(&configuration.Configuration{}, fm, fs)
+
+	assert.Nil(t, autoscaler.Step(start))
+	assert.True(t, autoscaler.table["/myapp"].Deploying)
+	assert.Empty(t, autoscaler.table["/myapp"].Statistics)
+
+	delete(fm.deployments, "d1")
+	assert.Nil(t, autoscaler.Step(start.Add(30*time.Second)))
+
+	application := autoscaler.table["/myapp"]
+	assert.False(t, application.Deploying)
+	assert.Equal(t, start.Add(90*time.Second), application.SettledAt)
+}
+
+func TestStepScrapesHostingAgents(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2014-10-03T23:00:00Z")
+
+	fm := newFakeMarathon()
+	fm.apps["/myapp"] = marathon.App{ID: "/myapp", Instances: 3,
+		Labels: map[string]string{"maxCPUTime": "50", "maxMemPercent": "50", "maxInstances": "10"}}
+	fm.apps["/other"] = marathon.App{ID: "/other", Instances: 1}
+	fm.tasks["task-1"] = marathon.Task{AppID: "/myapp", ID: "task-1", SlaveID: "S1"}
+	fm.tasks["task-2"] = marathon.Task{AppID: "/myapp", ID: "task-2"}
+	fm.tasks["task-3"] = marathon.Task{AppID: "/myapp", ID: "task-3", Host: "10.0.0.3"}
+	fm.tasks["other-1"] = marathon.Task{AppID: "/other", ID: "other-1", SlaveID: "S4"}
+
+	fs := newFakeMesos()
+	fs.agents["S1"] = mesos.Slave{ID: "S1", Hostname: "10.0.0.1"}
+	fs.agents["S2"] = mesos.Slave{ID: "S2", Hostname: "10.0.0.2"}
+	fs.agents["S3"] = mesos.Slave{ID: "S3", Hostname: "10.0.0.3"}
+	fs.agents["S4"] = mesos.Slave{ID: "S4", Hostname: "10.0.0.4"}
+	fs.agents["S5"] = mesos.Slave{ID: "S5"}
+	fs.tasks["task-2"] = mesos.Task{ID: "task-2", AgentID: "S2"}
+
+	autoscaler := New(&configuration.Configuration{}, fm, fs)
+	assert.Nil(t, autoscaler.Step(start))
+
+	assert.Equal(t, map[string]int{"S1": 1, "S2": 1, "S3": 1}, fs.scraped)
+}
+
+func TestStepSkipsFailingAgents(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2014-10-03T23:00:00Z")
+
+	fm := newFakeMarathon()
+	fm.apps["/myapp"] = marathon.App{ID: "/myapp", Instances: 2, CPUs: 0.5, Mem: 128,
+		Labels: map[string]string{"maxCPUTime": "50", "maxMemPercent": "50", "maxInstances": "10"}}
+	fm.tasks["task-1"] = marathon.Task{AppID: "/myapp", ID: "task-1", SlaveID: "S1", StartedAt: "2014-10-03T22:00:00Z"}
+	fm.tasks["task-2"] = marathon.Task{AppID: "/myapp", ID: "task-2", SlaveID: "S2", StartedAt: "2014-10-03T22:00:00Z"}
+
+	fs := newFakeMesos()
+	fs.agents["S1"] = mesos.Slave{ID: "S1"}
+	fs.agents["S2"] = mesos.Slave{ID: "S2"}
+	fs.statistics["S1"] = []mesos.Resource{sample("task-1", 100, 10, 600)}
+	fs.failing["S2"] = errors.New("connection refused")
+
+	autoscaler := New(&configuration.Configuration{}, fm, fs)
+	assert.Nil(t, autoscaler.Step(start))
+
+	assert.Equal(t, []string{"task-2"}, autoscaler.table["/myapp"].MissingStatistics)
+	assert.Len(t, autoscaler.table["/myapp"].Statistics, 1)
+}
+
+func TestStepAppliesLabelChanges(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2014-10-03T23:00:00Z")
+
+	fm := newFakeMarathon()
+	fm.apps["/myapp"] = marathon.App{ID: "/myapp", Instances: 2, CPUs: 0.5, Mem: 128,
+		Labels: map[string]string{"maxCPUTime": "90", "maxMemPercent": "90", "maxInstances": "10", "autoscaleMultiplier": "2"}}
+	fm.tasks["task-1"] = marathon.Task{AppID: "/myapp", ID: "task-1", SlaveID: "S1", StartedAt: "2014-10-03T22:00:00Z"}
+	fm.tasks["task-2"] = marathon.Task{AppID: "/myapp", ID: "task-2", SlaveID: "S1", StartedAt: "2014-10-03T22:00:00Z"}
+
+	fs := newFakeMesos()
+	fs.agents["S1"] = mesos.Slave{ID: "S1", Active: true,
+		UnReservedResources: mesos.SlaveResources{CPUS: 8, Mem: 8192}}
+
+	autoscaler := New(&configuration.Configuration{}, fm, fs)
+
+	fs.statistics["S1"] = []mesos.Resource{sample("task-1", 100, 10, 600), sample("task-2", 100, 10, 600)}
+	assert.Nil(t, autoscaler.Step(start))
+
+	// 60% CPU and memory stays under the original 90% thresholds
+	fs.statistics["S1"] = []mesos.Resource{sample("task-1", 110, 13, 600), sample("task-2", 110, 13, 600)}
+	assert.Nil(t, autoscaler.Step(start.Add(time.Minute)))
+	assert.Empty(t, fm.scaled)
+	assert.Equal(t, 90, autoscaler.table["/myapp"].MaxCPUTime)
+
+	fm.apps["/myapp"].Labels["maxCPUTime"] = "50"
+	fm.apps["/myapp"].Labels["maxMemPercent"] = "50"
+
+	fs.statistics["S1"] = []mesos.Resource{sample("task-1", 120, 16, 600), sample("task-2", 120, 16, 600)}
+	assert.Nil(t, autoscaler.Step(start.Add(2*time.Minute)))
+	assert.Equal(t, 50, autoscaler.table["/myapp"].MaxCPUTime)
+	assert.Equal(t, 4, fm.scaled["/myapp"])
+}
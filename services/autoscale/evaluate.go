@@ -0,0 +1,234 @@
+package autoscale
+
+import (
+	"math"
+	"time"
+
+	"github.com/rossmerr/marathon-autoscale/services/marathon"
+	"github.com/rossmerr/marathon-autoscale/services/mesos"
+)
+
+// maxDecisions kept in the history of each application
+const maxDecisions = 20
+
+// usage of an app's tasks averaged over the last interval
+type usage struct {
+	CPUPercent float64
+	MemPercent float64
+	// number of tasks the averages were computed from
+	Tasks int
+	// number of tasks left out for being unhealthy, staging or warming up
+	Excluded int
+	// percentage of the started tasks failing their health checks
+	UnhealthyPercent float64
+	// number of tasks the health ratio was computed from
+	HealthTasks int
+	// percentage of the CFS periods in which the tasks were throttled
+	ThrottlePercent float64
+	// number of tasks the throttling ratio was computed from
+	ThrottleTasks int
+	// network throughput per task in megabits and packets per second
+	NetRxMbps float64
+	NetTxMbps float64
+	NetRxPps  float64
+	NetTxPps  float64
+	// number of tasks the network throughput was computed from
+	NetTasks int
+	// custom metric aggregated across tasks
+	Metric float64
+	// number of tasks the custom metric was scraped from
+	MetricTasks int
+}
+
+// outcomes of the deployment started by a decision
+const (
+	deploymentFinished = "finished"
+	deploymentFailed   = "failed"
+	deploymentTimedOut = "timed out"
+)
+
+// decision taken by the autoscaler for an app
+type decision struct {
+	Time         time.Time
+	From         int
+	To           int
+	Usage        usage
+	DeploymentID string
+	Err          error
+	// outcome of the deployment, empty while it is in progress
+	Outcome string
+	// the target was lowered to what fits in the cluster
+	CapacityLimited bool
+}
+
+// appUsage computes the CPU and memory usage of each executor from its first
+// and last statistics samples and averages them across executors
+func appUsage(statistics []mesos.Resource) usage {
+	first := map[string]mesos.Statistics{}
+	last := map[string]mesos.Statistics{}
+
+	for _, resource := range statistics {
+		if _, ok := first[resource.ExecutorID]; !ok {
+			first[resource.ExecutorID] = resource.Statistics
+		}
+		last[resource.ExecutorID] = resource.Statistics
+	}
+
+	var u usage
+	for executorID, end := range last {
+		start := first[executorID]
+		elapsed := end.Timestamp - start.Timestamp
+		if elapsed <= 0 || end.CPUsLimit <= 0 || end.MemLimitBytes <= 0 {
+			continue
+		}
+
+		cpuTime := (end.CPUsUserTimeSecs + end.CPUsSystemTimeSecs) - (start.CPUsUserTimeSecs + start.CPUsSystemTimeSecs)
+		u.CPUPercent += cpuTime / elapsed / end.CPUsLimit * 100
+		u.MemPercent += float64(end.MemRssBytes) / float64(end.MemLimitBytes) * 100
+		u.Tasks++
+
+		// agents without CFS quotas report no periods
+		if periods := end.CPUsNrPeriods - start.CPUsNrPeriods; periods > 0 {
+			u.ThrottlePercent += float64(end.CPUsNrThrottled-start.CPUsNrThrottled) / float64(periods) * 100
+			u.ThrottleTasks++
+		}
+
+		// agents without the network port mapping isolator report no traffic
+		if end.HasNetwork {
+			u.NetRxMbps += float64(end.NetRxBytes-start.NetRxBytes) * 8 / 1e6 / elapsed
+			u.NetTxMbps += float64(end.NetTxBytes-start.NetTxBytes) * 8 / 1e6 / elapsed
+			u.NetRxPps += float64(end.NetRxPackets-start.NetRxPackets) / elapsed
+			u.NetTxPps += float64(end.NetTxPackets-start.NetTxPackets) / elapsed
+			u.NetTasks++
+		}
+	}
+
+	if u.Tasks > 0 {
+		u.CPUPercent /= float64(u.Tasks)
+		u.MemPercent /= float64(u.Tasks)
+	}
+
+	if u.ThrottleTasks > 0 {
+		u.ThrottlePercent /= float64(u.ThrottleTasks)
+	}
+
+	if u.NetTasks > 0 {
+		u.NetRxMbps /= float64(u.NetTasks)
+		u.NetTxMbps /= float64(u.NetTasks)
+		u.NetRxPps /= float64(u.NetTasks)
+		u.NetTxPps /= float64(u.NetTasks)
+	}
+
+	return u
+}
+
+// latestStatistics keeps only the last sample of each executor, the baseline
+// for the next interval
+func latestStatistics(statistics []mesos.Resource) []mesos.Resource {
+	index := map[string]int{}
+	p := []mesos.Resource{}
+	for _, v := range statistics {
+		if i, ok := index[v.ExecutorID]; ok {
+			p[i] = v
+			continue
+		}
+		index[v.ExecutorID] = len(p)
+		p = append(p, v)
+	}
+	return p
+}
+
+// targetInstances is the instance count after scaling out, capped at MaxInstances
+func (a application) targetInstances(instances int) int {
+	target := int(math.Ceil(float64(instances) * a.AutoscaleMultiplier))
+	if target > a.MaxInstances {
+		target = a.MaxInstances
+	}
+	return target
+}
+
+// record appends a decision to the history, dropping the oldest entries
+func (a *application) record(d decision) {
+	a.Decisions = append(a.Decisions, d)
+	if len(a.Decisions) > maxDecisions {
+		a.Decisions = a.Decisions[len(a.Decisions)-maxDecisions:]
+	}
+}
+
+// resolve records the outcome of the deployment on the decision that started
+// it, unless one was already recorded
+func (a *application) resolve(deploymentID string, outcome string) {
+	for i := range a.Decisions {
+		d := &a.Decisions[i]
+		if len(deploymentID) > 0 && d.DeploymentID == deploymentID && len(d.Outcome) == 0 {
+			d.Outcome = outcome
+			logger.Printf("Deployment %s scaling from %d to %d instances %s", d.DeploymentID, d.From, d.To, outcome)
+		}
+	}
+}
+
+// settle resolves the decisions whose deployments are no longer in progress,
+// as finished when the app kept the target instance count and failed otherwise
+func (a *application) settle(app marathon.App, deployments map[string]marathon.Deployment) {
+	for _, d := range a.Decisions {
+		if len(d.DeploymentID) == 0 || len(d.Outcome) > 0 {
+			continue
+		}
+
+		if _, ok := deployments[d.DeploymentID]; ok {
+			continue
+		}
+
+		if app.Instances == d.To {
+			a.resolve(d.DeploymentID, deploymentFinished)
+		} else {
+			a.resolve(d.DeploymentID, deploymentFailed)
+		}
+	}
+}
+
+// evaluate scales the app out when its usage over the last interval crosses
+// its thresholds
+func (a *Autoscaler) evaluate(app marathon.App, appTasks []marathon.Task, agents map[string]mesos.Slave, application application, now time.Time) application {
+	conf := a.conf
+	u := appUsage(application.Statistics)
+	u.Excluded = application.ExcludedTasks
+	u.UnhealthyPercent, u.HealthTasks = unhealthyPercent(app, appTasks, now, application.Warmup)
+	u.Metric, u.MetricTasks = application.MetricValue, application.MetricTasks
+	application.Statistics = latestStatistics(application.Statistics)
+
+	if !application.triggered(u) {
+		return application
+	}
+
+	target := application.targetInstances(app.Instances)
+
+	capacityLimited := false
+	if fits := placeableInstances(app, agents); app.Instances+fits < target {
+		logger.Printf("Cluster capacity limits %s to %d more instances, wanted %d", app.ID, fits, target-app.Instances)
+		target = app.Instances + fits
+		capacityLimited = true
+	}
+
+	if target <= app.Instances {
+		return application
+	}
+
+	d := decision{Time: now, From: app.Instances, To: target, Usage: u, CapacityLimited: capacityLimited}
+	d.DeploymentID, d.Err = a.marathon.ScaleApp(app, target)
+
+	// the deployment is followed by the next steps, through the deploying,
+	// settle and verification gates, rather than blocking the loop
+	if d.Err == nil {
+		application.Verifying = &verification{DeploymentID: d.DeploymentID, From: d.From, To: d.To, Deadline: now.Add(conf.Autoscale.VerifyPeriod())}
+	}
+
+	if d.Err != nil {
+		logger.Printf("Scaling %s from %d to %d instances failed: %s", app.ID, d.From, d.To, d.Err)
+	} else {
+		logger.Printf("Scaled %s from %d to %d instances (cpu %.1f%%, mem %.1f%%, %d tasks, %d excluded)", app.ID, d.From, d.To, u.CPUPercent, u.MemPercent, u.Tasks, u.Excluded)
+	}
+
+	application.record(d)
+	return application
+}
@@ -0,0 +1,121 @@
+package autoscale
+
+import (
+	"testing"
+
+	"github.com/rossmerr/marathon-autoscale/services/marathon"
+	"github.com/rossmerr/marathon-autoscale/services/mesos"
+	"github.com/stretchr/testify/assert"
+)
+
+func sample(executorID string, timestamp, cpuSecs float64, rss int) mesos.Resource {
+	return mesos.Resource{
+		ExecutorID: executorID,
+		Statistics: mesos.Statistics{
+			CPUsLimit:        0.5,
+			CPUsUserTimeSecs: cpuSecs,
+			MemLimitBytes:    1000,
+			MemRssBytes:      rss,
+			Timestamp:        timestamp,
+		},
+	}
+}
+
+func TestAppUsage(t *testing.T) {
+	statistics := []mesos.Resource{
+		sample("task-1", 100, 10, 400),
+		sample("task-2", 100, 20, 800),
+		sample("task-1", 110, 13, 500),
+		sample("task-2", 110, 24, 900),
+	}
+
+	u := appUsage(statistics)
+
+	assert.Equal(t, 2, u.Tasks)
+	assert.InDelta(t, 70, u.CPUPercent, 0.001)
+	assert.InDelta(t, 70, u.MemPercent, 0.001)
+
+	latest := latestStatistics(statistics)
+	assert.Len(t, latest, 2)
+	assert.Equal(t, 110.0, latest[0].Statistics.Timestamp)
+}
+
+func TestAppUsageThrottle(t *testing.T) {
+	throttled := func(executorID string, timestamp float64, periods, nrThrottled int) mesos.Resource {
+		resource := sample(executorID, timestamp, timestamp/10, 100)
+		resource.Statistics.CPUsNrPeriods = periods
+		resource.Statistics.CPUsNrThrottled = nrThrottled
+		return resource
+	}
+
+	statistics := []mesos.Resource{
+		throttled("task-1", 100, 1000, 10),
+		throttled("task-2", 100, 2000, 0),
+		throttled("task-3", 100, 0, 0),
+		throttled("task-1", 110, 1100, 30),
+		throttled("task-2", 110, 2100, 10),
+		throttled("task-3", 110, 0, 0),
+	}
+
+	u := appUsage(statistics)
+
+	assert.Equal(t, 3, u.Tasks)
+	assert.Equal(t, 2, u.ThrottleTasks)
+	assert.InDelta(t, 15, u.ThrottlePercent, 0.001)
+}
+
+func TestAppUsageNetwork(t *testing.T) {
+	traffic := func(executorID string, timestamp float64, rxBytes, rxPackets int) mesos.Resource {
+		resource := sample(executorID, timestamp, timestamp/10, 100)
+		resource.Statistics.NetRxBytes = rxBytes
+		resource.Statistics.NetRxPackets = rxPackets
+		resource.Statistics.HasNetwork = true
+		return resource
+	}
+
+	statistics := []mesos.Resource{
+		traffic("task-1", 100, 0, 0),
+		traffic("task-2", 100, 0, 0),
+		traffic("task-1", 110, 25000000, 20000),
+		traffic("task-2", 110, 0, 0),
+		sample("task-3", 100, 10, 100),
+		sample("task-3", 110, 11, 100),
+	}
+
+	u := appUsage(statistics)
+
+	// the idle task-2 counts towards the average, task-3 without counters does not
+	assert.Equal(t, 3, u.Tasks)
+	assert.Equal(t, 2, u.NetTasks)
+	assert.InDelta(t, 10, u.NetRxMbps, 0.001)
+	assert.InDelta(t, 1000, u.NetRxPps, 0.001)
+	assert.Equal(t, 0.0, u.NetTxMbps)
+}
+
+func TestTargetInstances(t *testing.T) {
+	app := application{MaxInstances: 5, AutoscaleMultiplier: 1.5}
+
+	assert.Equal(t, 3, app.targetInstances(2))
+	assert.Equal(t, 5, app.targetInstances(4))
+}
+
+func TestSettle(t *testing.T) {
+	application := application{Decisions: []decision{
+		{From: 1, To: 2, DeploymentID: "deployment-1"},
+		{From: 2, To: 4, DeploymentID: "deployment-2"},
+		{From: 4, To: 2, DeploymentID: "deployment-3"},
+		{From: 2, To: 3, Err: assert.AnError},
+	}}
+	deployments := map[string]marathon.Deployment{"deployment-3": {ID: "deployment-3"}}
+
+	application.settle(marathon.App{ID: "/myapp", Instances: 2}, deployments)
+
+	assert.Equal(t, deploymentFinished, application.Decisions[0].Outcome)
+	assert.Equal(t, deploymentFailed, application.Decisions[1].Outcome)
+	assert.Empty(t, application.Decisions[2].Outcome)
+	assert.Empty(t, application.Decisions[3].Outcome)
+
+	application.settle(marathon.App{ID: "/myapp", Instances: 2}, map[string]marathon.Deployment{})
+
+	assert.Equal(t, deploymentFinished, application.Decisions[2].Outcome)
+}
@@ -0,0 +1,124 @@
+package autoscale
+
+import (
+	"strings"
+
+	"github.com/rossmerr/marathon-autoscale/services/marathon"
+	"github.com/rossmerr/marathon-autoscale/services/mesos"
+)
+
+// statisticsIndex looks up the container statistics of a step by the IDs
+// tasks are matched on, holding the positions of the samples in resources
+type statisticsIndex struct {
+	resources   []mesos.Resource
+	byContainer map[string][]int
+	byExecutor  map[string][]int
+	bySource    map[string][]int
+}
+
+func newStatisticsIndex(resources []mesos.Resource) statisticsIndex {
+	index := statisticsIndex{
+		resources:   resources,
+		byContainer: map[string][]int{},
+		byExecutor:  map[string][]int{},
+		bySource:    map[string][]int{},
+	}
+
+	for i, resource := range resources {
+		if len(resource.ContainerID) > 0 {
+			index.byContainer[resource.ContainerID] = append(index.byContainer[resource.ContainerID], i)
+		}
+		if len(resource.ExecutorID) > 0 {
+			index.byExecutor[resource.ExecutorID] = append(index.byExecutor[resource.ExecutorID], i)
+		}
+		if len(resource.Source) > 0 {
+			index.bySource[resource.Source] = append(index.bySource[resource.Source], i)
+		}
+	}
+
+	return index
+}
+
+// matchStatistics returns the container statistics of the tasks, and the
+// tasks no statistics were found for. Each task is matched by the first of
+// these rules that finds statistics:
+//
+//	container ID  the container Mesos reports running the task
+//	executor ID   the executor Mesos reports running the task
+//	task ID       as executor ID, for the command executor and Docker containerizer
+//	task ID       as source, which custom executors usually set
+//	instance ID   the executor of the pod instance the task belongs to
+//
+// When Mesos reports the task's framework, statistics of other frameworks are
+// ignored. Pod containers share their executor's statistics, returned once.
+func (index statisticsIndex) matchStatistics(tasks []marathon.Task, mesosTasks map[string]mesos.Task) ([]mesos.Resource, []marathon.Task) {
+	matched := []mesos.Resource{}
+	missing := []marathon.Task{}
+	seen := map[int]bool{}
+
+	for _, task := range tasks {
+		mesosTask := mesosTasks[task.ID]
+		podExecutor, _ := podExecutorID(task.ID)
+
+		candidates := [][]int{
+			index.lookup(index.byContainer, mesosTask.ContainerID),
+			index.lookup(index.byExecutor, mesosTask.ExecutorID),
+			index.byExecutor[task.ID],
+			index.bySource[task.ID],
+			index.lookup(index.byExecutor, podExecutor),
+		}
+
+		found := false
+		for _, positions := range candidates {
+			for _, i := range positions {
+				if !sameFramework(index.resources[i], mesosTask) {
+					continue
+				}
+				found = true
+				if !seen[i] {
+					seen[i] = true
+					matched = append(matched, index.resources[i])
+				}
+			}
+			if found {
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, task)
+		}
+	}
+
+	return matched, missing
+}
+
+// lookup returns the positions indexed under id, none for an empty id
+func (index statisticsIndex) lookup(ids map[string][]int, id string) []int {
+	if len(id) == 0 {
+		return nil
+	}
+	return ids[id]
+}
+
+// sameFramework is false when both the statistics and Mesos name the
+// framework of the task and they differ
+func sameFramework(resource mesos.Resource, task mesos.Task) bool {
+	return len(resource.FrameworkID) == 0 || len(task.FrameworkID) == 0 || resource.FrameworkID == task.FrameworkID
+}
+
+// podExecutorID returns the executor ID Marathon gives the instance of a pod
+// task, e.g. instance-mypod.<uuid> for the task mypod.instance-<uuid>.container
+func podExecutorID(taskID string) (string, bool) {
+	index := strings.Index(taskID, ".instance-")
+	if index == -1 {
+		return "", false
+	}
+
+	uuid := taskID[index+len(".instance-"):]
+	if dot := strings.Index(uuid, "."); dot != -1 {
+		uuid = uuid[:dot]
+	}
+
+	return "instance-" + taskID[:index] + "." + uuid, true
+}
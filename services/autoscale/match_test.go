@@ -0,0 +1,49 @@
+package autoscale
+
+import (
+	"testing"
+
+	"github.com/rossmerr/marathon-autoscale/services/marathon"
+	"github.com/rossmerr/marathon-autoscale/services/mesos"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchStatistics(t *testing.T) {
+	container := sample("marathon-executor-1", 100, 1, 100)
+	container.ContainerID = "container-1"
+	custom := sample("custom-executor", 100, 1, 100)
+	custom.Source = "app.task-3"
+	pod := sample("instance-mypod.1234", 100, 1, 100)
+	otherFramework := sample("app.task-5", 100, 1, 100)
+	otherFramework.FrameworkID = "other"
+
+	resources := []mesos.Resource{container, sample("app.task-2", 100, 1, 100), custom, pod, otherFramework}
+
+	tasks := []marathon.Task{
+		{ID: "app.task-1"},
+		{ID: "app.task-2"},
+		{ID: "app.task-3"},
+		{ID: "mypod.instance-1234.web"},
+		{ID: "mypod.instance-1234.sidecar"},
+		{ID: "app.task-5"},
+	}
+
+	mesosTasks := map[string]mesos.Task{
+		"app.task-1": {ID: "app.task-1", ContainerID: "container-1"},
+		"app.task-5": {ID: "app.task-5", FrameworkID: "marathon"},
+	}
+
+	matched, missing := newStatisticsIndex(resources).matchStatistics(tasks, mesosTasks)
+
+	assert.Equal(t, []mesos.Resource{container, resources[1], custom, pod}, matched)
+	assert.Equal(t, []marathon.Task{{ID: "app.task-5"}}, missing)
+}
+
+func TestPodExecutorID(t *testing.T) {
+	executorID, ok := podExecutorID("group_mypod.instance-6e4b4f2a-b5c2-11e6-8310-0efb52840a34.web")
+	assert.True(t, ok)
+	assert.Equal(t, "instance-group_mypod.6e4b4f2a-b5c2-11e6-8310-0efb52840a34", executorID)
+
+	_, ok = podExecutorID("myapp.d2060420-b541-11e6-8310-0efb52840a34")
+	assert.False(t, ok)
+}
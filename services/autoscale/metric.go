@@ -0,0 +1,81 @@
+package autoscale
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rossmerr/marathon-autoscale/services/marathon"
+	"github.com/rossmerr/marathon-autoscale/services/metrics"
+)
+
+// metricWorkers bounds the concurrent scrapes of an app's tasks
+const metricWorkers = 8
+
+// metricTimeout of each scrape, short so hung tasks do not stall the step
+var metricTimeout = 2 * time.Second
+
+// scrapeMetric scrapes the app's custom metric from its tasks concurrently
+// and aggregates the values, returning the number of tasks that answered
+func (a *Autoscaler) scrapeMetric(application application, tasks []marathon.Task) (float64, int) {
+	work := make(chan marathon.Task)
+	results := make(chan float64, len(tasks))
+
+	var wg sync.WaitGroup
+	for i := 0; i < metricWorkers && i < len(tasks); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range work {
+				ctx, cancel := context.WithTimeout(context.Background(), metricTimeout)
+				value, err := metrics.Scrape(ctx, a.conf, application.Metric, task.Host, task.Ports)
+				cancel()
+				if err != nil {
+					logger.Printf("Scraping %s of task %s failed: %v", application.Metric.Name, task.ID, err)
+					continue
+				}
+				results <- value
+			}
+		}()
+	}
+
+	for _, task := range tasks {
+		work <- task
+	}
+	close(work)
+	wg.Wait()
+	close(results)
+
+	values := []float64{}
+	for value := range results {
+		values = append(values, value)
+	}
+
+	return aggregate(values, application.MetricAggregation), len(values)
+}
+
+// aggregate combines the values of the tasks: avg for a per-instance value,
+// sum for the app total or max for the busiest task
+func aggregate(values []float64, aggregation string) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	result := values[0]
+	for _, value := range values[1:] {
+		switch aggregation {
+		case "max":
+			if value > result {
+				result = value
+			}
+		default:
+			result += value
+		}
+	}
+
+	if aggregation != "sum" && aggregation != "max" {
+		result /= float64(len(values))
+	}
+
+	return result
+}
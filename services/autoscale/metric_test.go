@@ -0,0 +1,126 @@
+package autoscale
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/rossmerr/marathon-autoscale/services/marathon"
+	"github.com/rossmerr/marathon-autoscale/services/mesos"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregate(t *testing.T) {
+	values := []float64{2, 8, 5}
+
+	assert.Equal(t, 5.0, aggregate(values, "avg"))
+	assert.Equal(t, 5.0, aggregate(values, ""))
+	assert.Equal(t, 15.0, aggregate(values, "sum"))
+	assert.Equal(t, 8.0, aggregate(values, "max"))
+	assert.Equal(t, 0.0, aggregate(nil, "avg"))
+}
+
+func TestScrapeMetricSkipsHungTasks(t *testing.T) {
+	defer func(timeout time.Duration) { metricTimeout = timeout }(metricTimeout)
+	metricTimeout = 50 * time.Millisecond
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "requests_in_flight 4\n")
+	}))
+	defer ts.Close()
+
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hung.Close()
+	defer close(release)
+
+	task := func(id string, server *httptest.Server) marathon.Task {
+		u, _ := url.Parse(server.URL)
+		port, _ := strconv.Atoi(u.Port())
+		return marathon.Task{ID: id, Host: u.Hostname(), Ports: []int{port}}
+	}
+
+	tasks := []marathon.Task{task("task-1", ts), task("task-2", hung), task("task-3", ts)}
+	for i := 4; i <= 20; i++ {
+		tasks = append(tasks, task("task-"+strconv.Itoa(i), hung))
+	}
+
+	app, ok := newApplication(&configuration.Configuration{}, map[string]string{"maxInstances": "10",
+		"metricPath": "/metrics", "metricName": "requests_in_flight", "maxMetricValue": "10"}, marathon.App{ID: "/myapp"})
+	assert.True(t, ok)
+
+	autoscaler := New(&configuration.Configuration{}, newFakeMarathon(), newFakeMesos())
+	value, answered := autoscaler.scrapeMetric(app, tasks)
+
+	assert.Equal(t, 2, answered)
+	assert.Equal(t, 4.0, value)
+}
+
+func TestMetricPolicy(t *testing.T) {
+	labels := map[string]string{"maxInstances": "10", "metricPath": "/metrics", "metricName": "requests_in_flight", "maxMetricValue": "10"}
+
+	app, ok := newApplication(&configuration.Configuration{}, labels, marathon.App{ID: "/myapp"})
+	assert.True(t, ok)
+	assert.Equal(t, "any", app.TriggerMode)
+	assert.Equal(t, "avg", app.MetricAggregation)
+	assert.Equal(t, map[string]bool{"metric": false}, app.triggers(usage{}))
+
+	labels["maxCPUTime"], labels["maxMemPercent"] = "80", "80"
+	app, ok = newApplication(&configuration.Configuration{}, labels, marathon.App{ID: "/myapp"})
+	assert.True(t, ok)
+	assert.Equal(t, "both", app.TriggerMode)
+	assert.True(t, app.Metric.Enabled())
+
+	for label, value := range map[string]string{"metricAggregation": "median", "metricFormat": "xml", "metricPortIndex": "x"} {
+		invalid := map[string]string{label: value}
+		for k, v := range labels {
+			if _, ok := invalid[k]; !ok {
+				invalid[k] = v
+			}
+		}
+		_, _, _, err := metricPolicy(invalid)
+		assert.Error(t, err, label)
+
+		app, ok = newApplication(&configuration.Configuration{}, invalid, marathon.App{ID: "/myapp"})
+		assert.True(t, ok)
+		assert.False(t, app.Metric.Enabled(), label)
+	}
+}
+
+func TestStepScalesOnMetric(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2014-10-03T23:00:00Z")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/metrics", r.URL.Path)
+		fmt.Fprint(w, `{"requests": {"inFlight": 30}}`)
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	fm := newFakeMarathon()
+	fm.apps["/myapp"] = marathon.App{ID: "/myapp", Instances: 2, CPUs: 0.5, Mem: 128,
+		Labels: map[string]string{"maxInstances": "10", "autoscaleMultiplier": "2", "metricPath": "/metrics",
+			"metricFormat": "json", "metricName": "requests.inFlight", "maxMetricValue": "25"}}
+	fm.tasks["task-1"] = marathon.Task{AppID: "/myapp", ID: "task-1", SlaveID: "S1", Host: u.Hostname(),
+		Ports: []int{port}, StartedAt: "2014-10-03T22:00:00Z"}
+
+	fs := newFakeMesos()
+	fs.agents["S1"] = mesos.Slave{ID: "S1", Active: true,
+		UnReservedResources: mesos.SlaveResources{CPUS: 8, Mem: 8192}}
+
+	autoscaler := New(&configuration.Configuration{}, fm, fs)
+	assert.Nil(t, autoscaler.Step(start))
+
+	assert.Equal(t, 4, fm.scaled["/myapp"])
+	assert.Equal(t, 30.0, autoscaler.table["/myapp"].MetricValue)
+	assert.Equal(t, 1, autoscaler.table["/myapp"].MetricTasks)
+}
@@ -0,0 +1,170 @@
+package autoscale
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/rossmerr/marathon-autoscale/services/marathon"
+	"github.com/rossmerr/marathon-autoscale/services/metrics"
+)
+
+// policyLabels returns the effective autoscale labels for an app.
+//
+// Precedence, highest first:
+//  1. the app's own Marathon labels
+//  2. the app policies in the configuration file
+//  3. the group policies in the configuration file, nearest group first
+func policyLabels(conf *configuration.Configuration, groupIDs []string, app marathon.App) map[string]string {
+	labels := conf.GroupPolicyLabels(groupIDs)
+	for key, value := range conf.PolicyLabels(app.ID) {
+		labels[key] = value
+	}
+	for key, value := range app.Labels {
+		labels[key] = value
+	}
+	return labels
+}
+
+// newApplication reads the autoscaling policy of the app from its labels,
+// false when the labels do not make it autoscaled
+func newApplication(conf *configuration.Configuration, labels map[string]string, app marathon.App) (application, bool) {
+	var maxMemPercent, maxCPUTime, maxInstances int
+	var triggerMode string
+	var autoscaleMultiplier float64
+	var maxNetRxMbps, maxNetTxMbps, maxNetRxPps, maxNetTxPps float64
+	var warmupSeconds, maxUnhealthyPercent, maxThrottlePercent int
+	var ok bool
+	var err error
+
+	metric, metricAggregation, maxMetricValue, err := metricPolicy(labels)
+	if err != nil {
+		logger.Printf("Ignoring the custom metric of %s: %v", app.ID, err)
+	}
+
+	if maxInstances, err = strconv.Atoi(labels["maxInstances"]); err != nil {
+		return application{}, false
+	}
+
+	if autoscaleMultiplier, err = strconv.ParseFloat(labels["autoscaleMultiplier"], 64); err != nil {
+		autoscaleMultiplier = 1.5
+	}
+
+	if maxUnhealthyPercent, err = strconv.Atoi(labels["maxUnhealthyPercent"]); err != nil {
+		maxUnhealthyPercent = 0
+	}
+
+	if maxThrottlePercent, err = strconv.Atoi(labels["maxThrottlePercent"]); err != nil {
+		maxThrottlePercent = 0
+	}
+
+	if maxNetRxMbps, err = strconv.ParseFloat(labels["maxNetRxMbps"], 64); err != nil {
+		maxNetRxMbps = 0
+	}
+
+	if maxNetTxMbps, err = strconv.ParseFloat(labels["maxNetTxMbps"], 64); err != nil {
+		maxNetTxMbps = 0
+	}
+
+	if maxNetRxPps, err = strconv.ParseFloat(labels["maxNetRxPps"], 64); err != nil {
+		maxNetRxPps = 0
+	}
+
+	if maxNetTxPps, err = strconv.ParseFloat(labels["maxNetTxPps"], 64); err != nil {
+		maxNetTxPps = 0
+	}
+
+	// a policy setting only other triggers, such as maxUnhealthyPercent or a
+	// custom metric, does not scale on cpu and mem
+	otherTriggers := metric.Enabled() || maxUnhealthyPercent > 0 || maxThrottlePercent > 0 ||
+		maxNetRxMbps > 0 || maxNetTxMbps > 0 || maxNetRxPps > 0 || maxNetTxPps > 0
+	withoutCPUMem := otherTriggers && len(labels["maxMemPercent"]) == 0 && len(labels["maxCPUTime"]) == 0
+
+	if withoutCPUMem {
+		maxMemPercent, maxCPUTime = -1, -1
+	} else {
+		if maxMemPercent, err = strconv.Atoi(labels["maxMemPercent"]); err != nil {
+			return application{}, false
+		}
+
+		if maxCPUTime, err = strconv.Atoi(labels["maxCPUTime"]); err != nil {
+			return application{}, false
+		}
+	}
+
+	if triggerMode, ok = labels["triggerMode"]; !ok {
+		triggerMode = "both"
+		if withoutCPUMem {
+			triggerMode = "any"
+		}
+	}
+
+	warmup := conf.Autoscale.WarmupPeriod()
+	if warmupSeconds, err = strconv.Atoi(labels["warmupSeconds"]); err == nil {
+		warmup = time.Duration(warmupSeconds) * time.Second
+	}
+
+	application := application{AppID: app.ID, MaxMemPercent: maxMemPercent, MaxCPUTime: maxCPUTime,
+		MaxInstances: maxInstances, TriggerMode: triggerMode, AutoscaleMultiplier: autoscaleMultiplier,
+		MaxUnhealthyPercent: maxUnhealthyPercent, MaxThrottlePercent: maxThrottlePercent,
+		MaxNetRxMbps: maxNetRxMbps, MaxNetTxMbps: maxNetTxMbps, MaxNetRxPps: maxNetRxPps, MaxNetTxPps: maxNetTxPps,
+		Warmup: warmup, Metric: metric, MetricAggregation: metricAggregation, MaxMetricValue: maxMetricValue}
+
+	return application, true
+}
+
+// metricPolicy reads the custom metric source of the app from its labels, a
+// disabled source when the threshold is not set:
+//
+//	maxMetricValue     threshold of the metric trigger
+//	metricPath         path of the metrics endpoint of each task, e.g. /metrics
+//	metricName         Prometheus metric, optionally with labels, or JSON field path
+//	metricFormat       prometheus, the default, or json
+//	metricPortIndex    index of the task port serving the endpoint, 0 by default
+//	metricAggregation  avg, the default, sum or max across tasks
+//
+// The metric trigger is named metric. A policy setting neither maxCPUTime nor
+// maxMemPercent scales on the metric and the other configured triggers alone.
+func metricPolicy(labels map[string]string) (metrics.Source, string, float64, error) {
+	maxMetricValue, err := strconv.ParseFloat(labels["maxMetricValue"], 64)
+	if err != nil {
+		return metrics.Source{}, "", 0, nil
+	}
+
+	portIndex := 0
+	if label, ok := labels["metricPortIndex"]; ok {
+		if portIndex, err = strconv.Atoi(label); err != nil || portIndex < 0 {
+			return metrics.Source{}, "", 0, errors.New("Invalid metricPortIndex " + label)
+		}
+	}
+
+	aggregation := labels["metricAggregation"]
+	switch aggregation {
+	case "":
+		aggregation = "avg"
+	case "avg", "sum", "max":
+	default:
+		return metrics.Source{}, "", 0, errors.New("Unknown metricAggregation " + aggregation)
+	}
+
+	format := labels["metricFormat"]
+	switch format {
+	case "", "prometheus", "json":
+	default:
+		return metrics.Source{}, "", 0, errors.New("Unknown metricFormat " + format)
+	}
+
+	source := metrics.Source{
+		Path:      labels["metricPath"],
+		PortIndex: portIndex,
+		Format:    format,
+		Name:      labels["metricName"],
+	}
+
+	if !source.Enabled() {
+		return metrics.Source{}, "", 0, errors.New("maxMetricValue needs metricPath and metricName")
+	}
+
+	return source, aggregation, maxMetricValue, nil
+}
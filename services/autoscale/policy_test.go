@@ -0,0 +1,62 @@
+package autoscale
+
+import (
+	"testing"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/rossmerr/marathon-autoscale/services/marathon"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyLabels(t *testing.T) {
+	conf := &configuration.Configuration{}
+	conf.Policies = []configuration.Policy{
+		{AppID: "/product/*/service/*", Labels: map[string]string{"maxInstances": "5", "maxCPUTime": "50", "triggerMode": "cpu"}},
+		{AppID: "/product/*/service/myapp", Labels: map[string]string{"maxInstances": "8"}},
+		{AppID: "/product/us-east/service/myapp", Labels: map[string]string{"maxCPUTime": "70"}},
+	}
+
+	app := marathon.App{ID: "/product/us-east/service/myapp", Labels: map[string]string{"triggerMode": "mem"}}
+
+	labels := policyLabels(conf, nil, app)
+
+	assert.Equal(t, "5", labels["maxInstances"])
+	assert.Equal(t, "70", labels["maxCPUTime"])
+	assert.Equal(t, "mem", labels["triggerMode"])
+}
+
+func TestPolicyLabelsNoMatch(t *testing.T) {
+	conf := &configuration.Configuration{}
+	conf.Policies = []configuration.Policy{
+		{AppID: "/product/*/service/*", Labels: map[string]string{"maxInstances": "5"}},
+	}
+
+	app := marathon.App{ID: "/product/us-east/worker/myapp"}
+
+	labels := policyLabels(conf, nil, app)
+
+	assert.Empty(t, labels)
+}
+
+func TestPolicyLabelsGroupInheritance(t *testing.T) {
+	conf := &configuration.Configuration{}
+	conf.Policies = []configuration.Policy{
+		{GroupID: "/product/us-east", Labels: map[string]string{"maxCPUTime": "60", "triggerMode": "cpu"}},
+		{GroupID: "/product", Labels: map[string]string{"maxInstances": "10", "maxCPUTime": "40", "maxMemPercent": "80"}},
+		{AppID: "/product/*/service/*", Labels: map[string]string{"maxInstances": "5"}},
+		{GroupID: "/product/us-west", Labels: map[string]string{"maxInstances": "20"}},
+	}
+
+	app := marathon.App{ID: "/product/us-east/service/myapp", Labels: map[string]string{"maxMemPercent": "90"}}
+
+	labels := policyLabels(conf, []string{"/", "/product", "/product/us-east", "/product/us-east/service"}, app)
+
+	assert.Equal(t, "5", labels["maxInstances"])
+	assert.Equal(t, "60", labels["maxCPUTime"])
+	assert.Equal(t, "cpu", labels["triggerMode"])
+	assert.Equal(t, "90", labels["maxMemPercent"])
+
+	labels = policyLabels(conf, []string{"/", "/product"}, marathon.App{ID: "/product/worker"})
+
+	assert.Equal(t, map[string]string{"maxInstances": "10", "maxCPUTime": "40", "maxMemPercent": "80"}, labels)
+}
@@ -0,0 +1,137 @@
+package autoscale
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rossmerr/marathon-autoscale/services/marathon"
+)
+
+// triggers returns, by name, whether each trigger the app is configured with
+// fires for the usage. cpu and mem are configured unless the policy only sets
+// other thresholds, the other triggers only when their threshold label is set.
+func (a application) triggers(u usage) map[string]bool {
+	triggers := map[string]bool{}
+
+	if a.MaxCPUTime >= 0 {
+		triggers["cpu"] = u.Tasks > 0 && u.CPUPercent > float64(a.MaxCPUTime)
+	}
+
+	if a.MaxMemPercent >= 0 {
+		triggers["mem"] = u.Tasks > 0 && u.MemPercent > float64(a.MaxMemPercent)
+	}
+
+	if a.MaxUnhealthyPercent > 0 {
+		triggers["health"] = u.HealthTasks > 0 && u.UnhealthyPercent > float64(a.MaxUnhealthyPercent)
+	}
+
+	if a.MaxThrottlePercent > 0 {
+		triggers["throttle"] = u.ThrottleTasks > 0 && u.ThrottlePercent > float64(a.MaxThrottlePercent)
+	}
+
+	if a.Metric.Enabled() {
+		triggers["metric"] = u.MetricTasks > 0 && u.Metric > a.MaxMetricValue
+	}
+
+	if a.MaxNetRxMbps > 0 {
+		triggers["netRxMbps"] = u.NetTasks > 0 && u.NetRxMbps > a.MaxNetRxMbps
+	}
+
+	if a.MaxNetTxMbps > 0 {
+		triggers["netTxMbps"] = u.NetTasks > 0 && u.NetTxMbps > a.MaxNetTxMbps
+	}
+
+	if a.MaxNetRxPps > 0 {
+		triggers["netRxPps"] = u.NetTasks > 0 && u.NetRxPps > a.MaxNetRxPps
+	}
+
+	if a.MaxNetTxPps > 0 {
+		triggers["netTxPps"] = u.NetTasks > 0 && u.NetTxPps > a.MaxNetTxPps
+	}
+
+	return triggers
+}
+
+// triggered combines the app's triggers under its trigger mode:
+//
+//	both          cpu and mem fire, the default
+//	either        cpu or mem fires
+//	all, and      every configured trigger fires
+//	any, or       at least one configured trigger fires
+//	cpu,health    at least one of the listed triggers fires
+//
+// Under both and either, the other configured triggers fire on their own, so
+// setting a threshold label such as maxUnhealthyPercent is enough to scale on
+// it. The triggers are cpu, mem, health, throttle, netRxMbps, netTxMbps,
+// netRxPps, netTxPps and metric.
+func (a application) triggered(u usage) bool {
+	triggers := a.triggers(u)
+
+	switch a.TriggerMode {
+	case "both", "":
+		return triggers["cpu"] && triggers["mem"] || otherTriggered(triggers)
+	case "either":
+		return triggers["cpu"] || triggers["mem"] || otherTriggered(triggers)
+	case "all", "and":
+		for _, fired := range triggers {
+			if !fired {
+				return false
+			}
+		}
+		return true
+	case "any", "or":
+		for _, fired := range triggers {
+			if fired {
+				return true
+			}
+		}
+		return false
+	default:
+		for _, name := range strings.Split(a.TriggerMode, ",") {
+			if triggers[strings.TrimSpace(name)] {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// otherTriggered is true when a configured trigger other than cpu and mem fires
+func otherTriggered(triggers map[string]bool) bool {
+	for name, fired := range triggers {
+		if fired && name != "cpu" && name != "mem" {
+			return true
+		}
+	}
+	return false
+}
+
+// unhealthyPercent returns the percentage of the app's started tasks, past
+// their warm-up, that fail a health check, and the number of tasks considered
+func unhealthyPercent(app marathon.App, appTasks []marathon.Task, now time.Time, warmup time.Duration) (float64, int) {
+	if len(app.HealthChecks) == 0 {
+		return 0, 0
+	}
+
+	tasks, unhealthy := 0, 0
+	for _, task := range appTasks {
+		startedAt, ok := task.Started()
+		if !ok || now.Sub(startedAt) < warmup {
+			continue
+		}
+
+		tasks++
+		for _, result := range task.HealthCheckResults {
+			if !result.Alive || result.ConsecutiveFailures > 0 {
+				unhealthy++
+				break
+			}
+		}
+	}
+
+	if tasks == 0 {
+		return 0, 0
+	}
+
+	return float64(unhealthy) / float64(tasks) * 100, tasks
+}
@@ -0,0 +1,116 @@
+package autoscale
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rossmerr/marathon-autoscale/services/marathon"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTriggered(t *testing.T) {
+	app := application{MaxCPUTime: 60, MaxMemPercent: 80, TriggerMode: "both"}
+	u := usage{CPUPercent: 70, MemPercent: 50, Tasks: 1}
+
+	assert.False(t, app.triggered(u))
+
+	app.TriggerMode = "cpu"
+	assert.True(t, app.triggered(u))
+
+	app.TriggerMode = "either"
+	assert.True(t, app.triggered(u))
+
+	app.TriggerMode = "mem"
+	assert.False(t, app.triggered(u))
+}
+
+func TestTriggeredHealth(t *testing.T) {
+	app := application{MaxCPUTime: 60, MaxMemPercent: 80, MaxUnhealthyPercent: 20, TriggerMode: "cpu,health"}
+	u := usage{CPUPercent: 10, MemPercent: 10, Tasks: 4, UnhealthyPercent: 25, HealthTasks: 4}
+
+	assert.True(t, app.triggered(u))
+
+	app.TriggerMode = "both"
+	assert.True(t, app.triggered(u))
+
+	app.TriggerMode = "cpu,mem"
+	assert.False(t, app.triggered(u))
+
+	app.MaxUnhealthyPercent = 0
+	app.TriggerMode = "health"
+	assert.False(t, app.triggered(u))
+}
+
+func TestTriggeredBothAddsOtherTriggers(t *testing.T) {
+	app := application{MaxCPUTime: 60, MaxMemPercent: 80, MaxUnhealthyPercent: 20, MaxThrottlePercent: 10, TriggerMode: "both"}
+	u := usage{CPUPercent: 70, MemPercent: 90, Tasks: 2, HealthTasks: 2, ThrottleTasks: 2}
+
+	assert.True(t, app.triggered(u))
+
+	app.TriggerMode = "all"
+	assert.False(t, app.triggered(u))
+
+	u = usage{CPUPercent: 70, MemPercent: 10, Tasks: 2, HealthTasks: 2, UnhealthyPercent: 10}
+
+	app.TriggerMode = "both"
+	assert.False(t, app.triggered(u))
+
+	u = usage{CPUPercent: 10, MemPercent: 10, Tasks: 2, HealthTasks: 2, UnhealthyPercent: 50}
+
+	app.TriggerMode = "both"
+	assert.True(t, app.triggered(u))
+
+	app.TriggerMode = "either"
+	assert.True(t, app.triggered(u))
+
+	app.TriggerMode = "any"
+	assert.True(t, app.triggered(u))
+}
+
+func TestTriggeredThrottle(t *testing.T) {
+	app := application{MaxCPUTime: 60, MaxMemPercent: 80, MaxThrottlePercent: 10, TriggerMode: "throttle"}
+	u := usage{CPUPercent: 30, MemPercent: 10, Tasks: 2, ThrottlePercent: 15, ThrottleTasks: 2}
+
+	assert.True(t, app.triggered(u))
+
+	app.TriggerMode = "both"
+	assert.True(t, app.triggered(u))
+
+	u.ThrottleTasks = 0
+	app.TriggerMode = "throttle"
+	assert.False(t, app.triggered(u))
+}
+
+func TestTriggeredNetwork(t *testing.T) {
+	app := application{MaxCPUTime: 60, MaxMemPercent: 80, MaxNetRxMbps: 100, MaxNetTxPps: 5000, TriggerMode: "any"}
+	u := usage{CPUPercent: 10, MemPercent: 10, Tasks: 2, NetRxMbps: 120, NetTxPps: 1000, NetTasks: 2}
+
+	assert.True(t, app.triggered(u))
+
+	app.TriggerMode = "netTxPps"
+	assert.False(t, app.triggered(u))
+
+	app.TriggerMode = "netRxMbps,netTxPps"
+	assert.True(t, app.triggered(u))
+
+	u.NetTasks = 0
+	app.TriggerMode = "any"
+	assert.False(t, app.triggered(u))
+}
+
+func TestUnhealthyPercent(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2014-10-03T23:00:00Z")
+	app := marathon.App{ID: "/myapp", HealthChecks: []marathon.HealthCheck{{Protocol: "HTTP"}}}
+	tasks := []marathon.Task{
+		{ID: "healthy", StartedAt: "2014-10-03T22:50:00Z", HealthCheckResults: []marathon.HealthCheckResult{{Alive: true}}},
+		{ID: "failing", StartedAt: "2014-10-03T22:50:00Z", HealthCheckResults: []marathon.HealthCheckResult{{Alive: true, ConsecutiveFailures: 2}}},
+		{ID: "dead", StartedAt: "2014-10-03T22:50:00Z", HealthCheckResults: []marathon.HealthCheckResult{{Alive: false}}},
+		{ID: "warming-up", StartedAt: "2014-10-03T22:59:30Z", HealthCheckResults: []marathon.HealthCheckResult{{Alive: false}}},
+		{ID: "staging", StagedAt: "2014-10-03T22:59:00Z"},
+	}
+
+	percent, considered := unhealthyPercent(app, tasks, now, time.Minute)
+
+	assert.Equal(t, 3, considered)
+	assert.InDelta(t, 66.666, percent, 0.01)
+}
@@ -0,0 +1,91 @@
+package autoscale
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rossmerr/marathon-autoscale/services/marathon"
+)
+
+// verification of a scale-out, which is rolled back when the app does not
+// reach the target number of healthy tasks before the deadline
+type verification struct {
+	DeploymentID string
+	From         int
+	To           int
+	Deadline     time.Time
+	// the rollback failed and is retried every step, holding off scale-outs
+	RollingBack bool
+}
+
+// verify checks the app's tasks against a pending verification, rolling the
+// scale-out back and backing off once the deadline passes
+func (a *Autoscaler) verify(app marathon.App, appTasks []marathon.Task, application application, now time.Time) application {
+	v := application.Verifying
+
+	healthy := 0
+	for _, task := range appTasks {
+		if task.Healthy(app) {
+			healthy++
+		}
+	}
+
+	// the app was scaled by someone else since
+	if app.Instances != v.To {
+		application.Verifying = nil
+		return application
+	}
+
+	if v.RollingBack {
+		return a.rollback(app, application, healthy, now)
+	}
+
+	if healthy >= v.To {
+		application.resolve(v.DeploymentID, deploymentFinished)
+		application.Verifying = nil
+		return application
+	}
+
+	if now.Before(v.Deadline) {
+		return application
+	}
+
+	application.resolve(v.DeploymentID, deploymentTimedOut)
+
+	return a.rollback(app, application, healthy, now)
+}
+
+// rollback scales the app back to its instance count before the scale-out.
+// A failed rollback stays pending and is retried at the next step.
+func (a *Autoscaler) rollback(app marathon.App, application application, healthy int, now time.Time) application {
+	conf := a.conf
+	v := *application.Verifying
+
+	d := decision{Time: now, From: app.Instances, To: v.From}
+	d.DeploymentID, d.Err = a.marathon.ScaleApp(app, v.From)
+
+	// the scale-out deployment still holds the app lock while its tasks fail
+	if scaleErr, ok := d.Err.(*marathon.ScaleError); ok && scaleErr.Locked() {
+		d.DeploymentID, d.Err = a.marathon.ForceScaleApp(app, v.From)
+	}
+	application.record(d)
+
+	application.BackoffUntil = now.Add(conf.Autoscale.BackoffPeriod())
+
+	if d.Err != nil {
+		if !v.RollingBack {
+			alert(conf, app.ID, fmt.Sprintf("Only %d of %d tasks healthy after scaling out, rolling back to %d instances failed: %s", healthy, v.To, v.From, d.Err))
+		} else {
+			logger.Printf("Rolling %s back to %d instances failed again: %s", app.ID, v.From, d.Err)
+		}
+
+		v.RollingBack = true
+		application.Verifying = &v
+		return application
+	}
+
+	application.Verifying = nil
+	alert(conf, app.ID, fmt.Sprintf("Only %d of %d tasks healthy after scaling out, rolled back to %d instances", healthy, v.To, v.From))
+
+	return application
+}
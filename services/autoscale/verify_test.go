@@ -0,0 +1,93 @@
+package autoscale
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/rossmerr/marathon-autoscale/services/marathon"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyHealthy(t *testing.T) {
+	fm := newFakeMarathon()
+	autoscaler := New(&configuration.Configuration{}, fm, newFakeMesos())
+	now := time.Now()
+	app := marathon.App{ID: "/myapp", Instances: 2}
+	tasks := []marathon.Task{
+		{AppID: "/myapp", ID: "task-1", StartedAt: "2014-10-03T22:57:41.587Z"},
+		{AppID: "/myapp", ID: "task-2", StartedAt: "2014-10-03T22:57:41.587Z"},
+	}
+
+	application := application{
+		Verifying: &verification{DeploymentID: "deployment-1", From: 1, To: 2, Deadline: now.Add(time.Minute)},
+		Decisions: []decision{{From: 1, To: 2, DeploymentID: "deployment-1"}},
+	}
+	application = autoscaler.verify(app, tasks, application, now)
+
+	assert.Nil(t, application.Verifying)
+	assert.Len(t, application.Decisions, 1)
+	assert.Equal(t, deploymentFinished, application.Decisions[0].Outcome)
+	assert.Empty(t, fm.scaled)
+}
+
+func TestVerifyRollback(t *testing.T) {
+	var alerted alertMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&alerted)
+	}))
+	defer ts.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Autoscale.AlertWebhook = ts.URL
+	fm := newFakeMarathon()
+	autoscaler := New(conf, fm, newFakeMesos())
+	now := time.Now()
+	app := marathon.App{ID: "/myapp", Instances: 2, HealthChecks: []marathon.HealthCheck{{Protocol: "HTTP"}}}
+	tasks := []marathon.Task{
+		{AppID: "/myapp", ID: "task-1", StartedAt: "2014-10-03T22:57:41.587Z", HealthCheckResults: []marathon.HealthCheckResult{{Alive: true}}},
+		{AppID: "/myapp", ID: "task-2", StartedAt: "2014-10-03T22:57:41.587Z", HealthCheckResults: []marathon.HealthCheckResult{{Alive: false, ConsecutiveFailures: 3}}},
+	}
+
+	application := application{
+		Verifying: &verification{DeploymentID: "deployment-1", From: 1, To: 2, Deadline: now.Add(time.Minute)},
+		Decisions: []decision{{From: 1, To: 2, DeploymentID: "deployment-1"}},
+	}
+	application = autoscaler.verify(app, tasks, application, now)
+
+	assert.NotNil(t, application.Verifying)
+	assert.Empty(t, application.Decisions[0].Outcome)
+
+	application = autoscaler.verify(app, tasks, application, now.Add(2*time.Minute))
+
+	assert.Nil(t, application.Verifying)
+	assert.Equal(t, 1, fm.scaled["/myapp"])
+	assert.True(t, application.BackoffUntil.After(now))
+	assert.Equal(t, deploymentTimedOut, application.Decisions[0].Outcome)
+	assert.Equal(t, "deployment-/myapp", application.Decisions[1].DeploymentID)
+	assert.Empty(t, application.Decisions[1].Outcome)
+	assert.Equal(t, "/myapp", alerted.AppID)
+}
+
+func TestVerifyRollbackLocked(t *testing.T) {
+	fm := newFakeMarathon()
+	fm.locked["/myapp"] = true
+	autoscaler := New(&configuration.Configuration{}, fm, newFakeMesos())
+	now := time.Now()
+	app := marathon.App{ID: "/myapp", Instances: 2, HealthChecks: []marathon.HealthCheck{{Protocol: "HTTP"}}}
+	tasks := []marathon.Task{
+		{AppID: "/myapp", ID: "task-1", StartedAt: "2014-10-03T22:57:41.587Z", HealthCheckResults: []marathon.HealthCheckResult{{Alive: true}}},
+		{AppID: "/myapp", ID: "task-2", StartedAt: "2014-10-03T22:57:41.587Z"},
+	}
+
+	application := application{Verifying: &verification{From: 1, To: 2, Deadline: now}}
+	application = autoscaler.verify(app, tasks, application, now.Add(time.Minute))
+
+	assert.Equal(t, 1, fm.forced["/myapp"])
+	assert.Equal(t, 1, fm.scaled["/myapp"])
+	assert.Nil(t, application.Decisions[0].Err)
+	assert.Equal(t, "forced-deployment-/myapp", application.Decisions[0].DeploymentID)
+}
@@ -0,0 +1,210 @@
+package dcos
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/rossmerr/marathon-autoscale/services/httpclient"
+)
+
+// loginLifetime of the JWT the service account logs in with
+var loginLifetime = 5 * time.Minute
+
+// defaultTokenLifetime when the ACS token does not carry an expiry
+var defaultTokenLifetime = time.Hour
+
+// refreshMargin before the ACS token expires that a new one is requested
+var refreshMargin = time.Minute
+
+type token struct {
+	value   string
+	expires time.Time
+}
+
+// tokens by service account
+var tokens = map[configuration.ServiceAccount]token{}
+var tokensMu sync.Mutex
+
+type loginRequest struct {
+	UID   string `json:"uid"`
+	Token string `json:"token"`
+}
+
+type loginResponse struct {
+	Token string `json:"token"`
+}
+
+type claims struct {
+	UID string `json:"uid"`
+	Exp int64  `json:"exp"`
+}
+
+// Authorize adds the ACS token of the service account to the request,
+// logging in when there is no token yet or it is about to expire
+func Authorize(req *http.Request, account configuration.ServiceAccount) error {
+	value, err := Token(account)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "token="+value)
+	return nil
+}
+
+// Token returns the ACS token of the service account
+func Token(account configuration.ServiceAccount) (string, error) {
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+
+	if t, ok := tokens[account]; ok && time.Now().Add(refreshMargin).Before(t.expires) {
+		return t.value, nil
+	}
+
+	t, err := login(account)
+	if err != nil {
+		return "", err
+	}
+
+	tokens[account] = t
+	return t.value, nil
+}
+
+// Invalidate drops the cached token, e.g. after it was rejected with a 401
+func Invalidate(account configuration.ServiceAccount) {
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+
+	delete(tokens, account)
+}
+
+func login(account configuration.ServiceAccount) (token, error) {
+	key, err := privateKey(account.PrivateKeyFile)
+	if err != nil {
+		return token{}, err
+	}
+
+	signed, err := sign(key, claims{UID: account.UID, Exp: time.Now().Add(loginLifetime).Unix()})
+	if err != nil {
+		return token{}, err
+	}
+
+	body, err := json.Marshal(loginRequest{UID: account.UID, Token: signed})
+	if err != nil {
+		return token{}, err
+	}
+
+	client, err := httpclient.NewWithTimeout(account.TLS, configuration.HTTP{})
+	if err != nil {
+		return token{}, err
+	}
+
+	endpoint := strings.TrimRight(account.LoginEndpoint, "/") + "/acs/api/v1/auth/login"
+	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return token{}, err
+	}
+
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Content-Type", "application/json")
+	response, err := client.Do(req)
+	if err != nil {
+		return token{}, err
+	}
+
+	defer response.Body.Close()
+
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return token{}, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return token{}, fmt.Errorf("Service account login as %s failed with status %d: %s", account.UID, response.StatusCode, contents)
+	}
+
+	var result loginResponse
+	if err := json.Unmarshal(contents, &result); err != nil {
+		return token{}, err
+	}
+
+	return token{value: result.Token, expires: expiry(result.Token)}, nil
+}
+
+func privateKey(file string) (*rsa.PrivateKey, error) {
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, errors.New("No PEM private key found in " + file)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("Private key in " + file + " is not an RSA key")
+	}
+	return key, nil
+}
+
+// sign returns the RS256 JWT of the claims
+func sign(key *rsa.PrivateKey, c claims) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// expiry reads the exp claim of the ACS token, which is itself a JWT
+func expiry(value string) time.Time {
+	parts := strings.Split(value, ".")
+	if len(parts) == 3 {
+		var c claims
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err == nil && json.Unmarshal(payload, &c) == nil && c.Exp > 0 {
+			return time.Unix(c.Exp, 0)
+		}
+	}
+
+	return time.Now().Add(defaultTokenLifetime)
+}
@@ -0,0 +1,112 @@
+package dcos
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/stretchr/testify/assert"
+)
+
+// acsServer stands in for the DC/OS ACS login endpoint, verifying the login
+// JWT against the service account public key
+func acsServer(t *testing.T, key *rsa.PrivateKey, logins *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/acs/api/v1/auth/login", r.URL.Path)
+
+		var login loginRequest
+		json.NewDecoder(r.Body).Decode(&login)
+
+		parts := strings.Split(login.Token, ".")
+		assert.Len(t, parts, 3)
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		*logins++
+		payload, _ := json.Marshal(claims{UID: login.UID, Exp: time.Now().Add(time.Hour).Unix()})
+		acsToken := fmt.Sprintf("header.%s.signature-%d", base64.RawURLEncoding.EncodeToString(payload), *logins)
+		fmt.Fprintf(w, `{"token": "%s"}`, acsToken)
+	}))
+}
+
+func serviceAccount(t *testing.T, key *rsa.PrivateKey, loginEndpoint string) (configuration.ServiceAccount, func()) {
+	dir, err := ioutil.TempDir("", "dcos")
+	assert.Nil(t, err)
+
+	keyFile := filepath.Join(dir, "private-key.pem")
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	assert.Nil(t, ioutil.WriteFile(keyFile, pemKey, 0600))
+
+	account := configuration.ServiceAccount{UID: "marathon-autoscale", PrivateKeyFile: keyFile, LoginEndpoint: loginEndpoint}
+	return account, func() { os.RemoveAll(dir) }
+}
+
+func TestToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	logins := 0
+	ts := acsServer(t, key, &logins)
+	defer ts.Close()
+
+	account, cleanup := serviceAccount(t, key, ts.URL)
+	defer cleanup()
+
+	token, err := Token(account)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(token, "signature-1"))
+
+	token, err = Token(account)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, logins)
+
+	Invalidate(account)
+
+	req, _ := http.NewRequest("GET", "http://marathon.mesos/v2/apps", nil)
+	assert.Nil(t, Authorize(req, account))
+	assert.True(t, strings.HasSuffix(req.Header.Get("Authorization"), "signature-2"))
+	assert.True(t, strings.HasPrefix(req.Header.Get("Authorization"), "token=header."))
+}
+
+func TestTokenWrongKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	logins := 0
+	ts := acsServer(t, key, &logins)
+	defer ts.Close()
+
+	account, cleanup := serviceAccount(t, other, ts.URL)
+	defer cleanup()
+
+	_, err = Token(account)
+	assert.NotNil(t, err)
+}
+
+func TestExpiry(t *testing.T) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"uid": "marathon-autoscale", "exp": 1500000000}`))
+
+	assert.Equal(t, time.Unix(1500000000, 0), expiry("header."+payload+".signature"))
+	assert.True(t, expiry("opaque").After(time.Now()))
+}
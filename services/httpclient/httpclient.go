@@ -0,0 +1,153 @@
+package httpclient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+)
+
+// maxBackoff between two retries
+var maxBackoff = 30 * time.Second
+
+// StatusError is returned for responses outside the 2xx range
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s responded %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+type clientKey struct {
+	tls     configuration.TLS
+	timeout time.Duration
+}
+
+// clients by TLS configuration and timeout, sharing a transport per TLS
+// configuration so connections are reused across calls
+var clients = map[clientKey]*http.Client{}
+var clientsMu sync.Mutex
+
+// New returns the HTTP client without a timeout for endpoints with the given
+// TLS configuration, for long lived requests such as event streams
+func New(t configuration.TLS) (*http.Client, error) {
+	return client(clientKey{tls: t})
+}
+
+// NewWithTimeout returns the HTTP client for endpoints with the given TLS
+// configuration, timing out requests as configured
+func NewWithTimeout(t configuration.TLS, settings configuration.HTTP) (*http.Client, error) {
+	return client(clientKey{tls: t, timeout: settings.Timeout()})
+}
+
+func client(key clientKey) (*http.Client, error) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+
+	if c, ok := clients[key]; ok {
+		return c, nil
+	}
+
+	if c, ok := clients[clientKey{tls: key.tls}]; ok {
+		timed := &http.Client{Transport: c.Transport, Timeout: key.timeout}
+		clients[key] = timed
+		return timed, nil
+	}
+
+	tlsConfig, err := key.tls.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	clients[clientKey{tls: key.tls}] = &http.Client{Transport: transport}
+	c := &http.Client{Transport: transport, Timeout: key.timeout}
+	clients[key] = c
+	return c, nil
+}
+
+// Idempotent is true for the methods that are safe to retry
+func Idempotent(method string) bool {
+	return method == "GET" || method == "HEAD" || method == "OPTIONS"
+}
+
+// Retryable is true for connection errors, 5xx and 429 responses
+func Retryable(response *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+}
+
+// Retry calls attempt until it succeeds or the retries run out, waiting an
+// exponential backoff with jitter between attempts. Requests that are not
+// idempotent, such as scaling an app, are attempted once.
+func Retry(method string, settings configuration.HTTP, attempt func() (*http.Response, error)) (*http.Response, error) {
+	retries := 0
+	if Idempotent(method) {
+		retries = settings.RetryCount()
+	}
+
+	return retry(retries, settings, attempt)
+}
+
+// RetryRead is Retry for requests that only read state whatever their method,
+// such as the POST calls of the Mesos operator API
+func RetryRead(settings configuration.HTTP, attempt func() (*http.Response, error)) (*http.Response, error) {
+	return retry(settings.RetryCount(), settings, attempt)
+}
+
+func retry(retries int, settings configuration.HTTP, attempt func() (*http.Response, error)) (*http.Response, error) {
+	backoff := settings.Backoff()
+	for i := 0; ; i++ {
+		response, err := attempt()
+		if i >= retries || !Retryable(response, err) {
+			return response, err
+		}
+
+		if response != nil {
+			response.Body.Close()
+		}
+
+		time.Sleep(jitter(backoff))
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// jitter returns a random duration between half and all of the backoff
+func jitter(backoff time.Duration) time.Duration {
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// CheckStatus returns a StatusError, and closes the body, when the response
+// is outside the 2xx range
+func CheckStatus(response *http.Response) error {
+	if response.StatusCode >= 200 && response.StatusCode <= 299 {
+		return nil
+	}
+
+	defer response.Body.Close()
+	body, _ := ioutil.ReadAll(response.Body)
+
+	return &StatusError{
+		Method:     response.Request.Method,
+		URL:        response.Request.URL.String(),
+		StatusCode: response.StatusCode,
+		Body:       body,
+	}
+}
@@ -0,0 +1,147 @@
+package httpclient
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWithCAFile(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "httpclient")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	assert.Nil(t, ioutil.WriteFile(caFile, ca, 0600))
+
+	client, err := New(configuration.TLS{})
+	assert.Nil(t, err)
+	_, err = client.Get(ts.URL)
+	assert.NotNil(t, err)
+
+	client, err = New(configuration.TLS{CAFile: caFile, ServerName: "example.com"})
+	assert.Nil(t, err)
+	response, err := client.Get(ts.URL)
+	assert.Nil(t, err)
+	response.Body.Close()
+
+	same, _ := New(configuration.TLS{CAFile: caFile, ServerName: "example.com"})
+	assert.True(t, client == same)
+}
+
+func TestNewInsecure(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	}))
+	defer ts.Close()
+
+	client, err := New(configuration.TLS{Insecure: true})
+	assert.Nil(t, err)
+
+	response, err := client.Get(ts.URL)
+	assert.Nil(t, err)
+	response.Body.Close()
+}
+
+func TestNewMissingCAFile(t *testing.T) {
+	_, err := New(configuration.TLS{CAFile: "/nonexistent/ca.pem"})
+
+	assert.NotNil(t, err)
+}
+
+func TestRetryIdempotent(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	}))
+	defer ts.Close()
+
+	settings := configuration.HTTP{BackoffMillis: 1}
+	client, err := NewWithTimeout(configuration.TLS{}, settings)
+	assert.Nil(t, err)
+
+	response, err := Retry("GET", settings, func() (*http.Response, error) {
+		return client.Get(ts.URL)
+	})
+
+	assert.Nil(t, err)
+	assert.Nil(t, CheckStatus(response))
+	assert.Equal(t, 3, calls)
+	response.Body.Close()
+}
+
+func TestRetryNotIdempotent(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, "unavailable")
+	}))
+	defer ts.Close()
+
+	settings := configuration.HTTP{BackoffMillis: 1}
+	client, err := NewWithTimeout(configuration.TLS{}, settings)
+	assert.Nil(t, err)
+
+	response, err := Retry("PUT", settings, func() (*http.Response, error) {
+		req, _ := http.NewRequest("PUT", ts.URL+"/v2/apps/myapp", nil)
+		return client.Do(req)
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, calls)
+
+	statusErr, ok := CheckStatus(response).(*StatusError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+	assert.Equal(t, "PUT", statusErr.Method)
+	assert.Equal(t, "unavailable\n", string(statusErr.Body))
+}
+
+func TestRetriesExhausted(t *testing.T) {
+	calls := 0
+	settings := configuration.HTTP{Retries: 2, BackoffMillis: 1}
+
+	_, err := Retry("GET", settings, func() (*http.Response, error) {
+		calls++
+		return nil, fmt.Errorf("connection refused")
+	})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	client, err := NewWithTimeout(configuration.TLS{}, configuration.HTTP{})
+	assert.Nil(t, err)
+	assert.Equal(t, 10*time.Second, client.Timeout)
+
+	short := &http.Client{Transport: client.Transport, Timeout: 10 * time.Millisecond}
+	_, err = short.Get(ts.URL)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,64 @@
+package marathon
+
+import "github.com/rossmerr/marathon-autoscale/configuration"
+
+// Client of the configured Marathon endpoints, serving apps, tasks, groups and
+// deployments from the event stream mirror when Marathon.EventStream is set
+type Client struct {
+	conf   *configuration.Configuration
+	mirror *Mirror
+}
+
+// NewClient returns the client, starting the event stream mirror when configured
+func NewClient(conf *configuration.Configuration) *Client {
+	client := &Client{conf: conf}
+	if conf.Marathon.EventStream {
+		client.mirror = NewMirror(conf)
+		go client.mirror.Run(nil)
+	}
+	return client
+}
+
+func (c *Client) FetchApps() (map[string]App, error) {
+	if c.mirror != nil {
+		if apps, _, ok := c.mirror.Snapshot(); ok {
+			return apps, nil
+		}
+	}
+	return FetchApps(c.conf)
+}
+
+func (c *Client) FetchTasks() (map[string]Task, error) {
+	if c.mirror != nil {
+		if _, tasks, ok := c.mirror.Snapshot(); ok {
+			return tasks, nil
+		}
+	}
+	return FetchTasks(c.conf)
+}
+
+func (c *Client) FetchGroups() (Group, error) {
+	if c.mirror != nil {
+		if groups, ok := c.mirror.Groups(); ok {
+			return groups, nil
+		}
+	}
+	return FetchGroups(c.conf)
+}
+
+func (c *Client) FetchDeployments() (map[string]Deployment, error) {
+	if c.mirror != nil {
+		if deployments, ok := c.mirror.Deployments(); ok {
+			return deployments, nil
+		}
+	}
+	return FetchDeployments(c.conf)
+}
+
+func (c *Client) ScaleApp(app App, instances int) (string, error) {
+	return app.ScaleApp(c.conf, instances)
+}
+
+func (c *Client) ForceScaleApp(app App, instances int) (string, error) {
+	return app.ForceScaleApp(c.conf, instances)
+}
@@ -0,0 +1,272 @@
+package marathon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/rossmerr/marathon-autoscale/services/dcos"
+	"github.com/rossmerr/marathon-autoscale/services/httpclient"
+)
+
+// errNoEndpoints is returned when no Marathon endpoint is configured
+var errNoEndpoints = errors.New("No Marathon endpoint configured")
+
+// unhealthyPeriod an endpoint is tried last after it failed
+var unhealthyPeriod = 30 * time.Second
+
+// endpointPool tracks the health of the Marathon endpoints and the current leader
+type endpointPool struct {
+	mu        sync.Mutex
+	endpoints []string
+	leader    string
+	checkedAt time.Time
+	failedAt  map[string]time.Time
+}
+
+var pools = map[string]*endpointPool{}
+var poolsMu sync.Mutex
+
+// poolFor returns the endpoint pool of the configured Marathon endpoints
+func poolFor(conf *configuration.Configuration) *endpointPool {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	pool, ok := pools[conf.Marathon.Endpoint]
+	if !ok {
+		pool = &endpointPool{failedAt: map[string]time.Time{}}
+		for _, endpoint := range conf.Marathon.Endpoints() {
+			endpoint = strings.TrimRight(strings.TrimSpace(endpoint), "/")
+			if len(endpoint) > 0 {
+				pool.endpoints = append(pool.endpoints, endpoint)
+			}
+		}
+		pools[conf.Marathon.Endpoint] = pool
+	}
+	return pool
+}
+
+// order returns the endpoints to try: the leader, then the healthy endpoints,
+// then the ones that failed recently
+func (p *endpointPool) order() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	healthy := []string{}
+	unhealthy := []string{}
+
+	for _, endpoint := range p.endpoints {
+		switch {
+		case endpoint == p.leader:
+			healthy = append([]string{endpoint}, healthy...)
+		case now.Sub(p.failedAt[endpoint]) < unhealthyPeriod:
+			unhealthy = append(unhealthy, endpoint)
+		default:
+			healthy = append(healthy, endpoint)
+		}
+	}
+
+	return append(healthy, unhealthy...)
+}
+
+func (p *endpointPool) markFailed(endpoint string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.failedAt[endpoint] = time.Now()
+	if p.leader == endpoint {
+		p.leader = ""
+	}
+}
+
+func (p *endpointPool) markHealthy(endpoint string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.failedAt, endpoint)
+}
+
+// needsLeader is true when there are several endpoints, none known to be the
+// leader, and the leader was not looked up recently
+func (p *endpointPool) needsLeader() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.endpoints) > 1 && len(p.leader) == 0 && time.Since(p.checkedAt) > unhealthyPeriod
+}
+
+// setLeader records the endpoint whose host matches the leader reported by
+// /v2/leader. Leaders not in the endpoint list, e.g. behind a load balancer,
+// are ignored.
+func (p *endpointPool) setLeader(leader string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, endpoint := range p.endpoints {
+		if u, err := url.Parse(endpoint); err == nil && u.Host == leader {
+			p.leader = endpoint
+			return
+		}
+	}
+}
+
+// discoverLeader asks the first endpoint that answers for the current leader
+func (p *endpointPool) discoverLeader(conf *configuration.Configuration, client *http.Client) {
+	var leader struct {
+		Leader string `json:"leader"`
+	}
+
+	p.mu.Lock()
+	p.checkedAt = time.Now()
+	p.mu.Unlock()
+
+	for _, endpoint := range p.order() {
+		response, err := send(conf, client, endpoint, "GET", "/v2/leader", nil, "application/json")
+		if err != nil {
+			p.markFailed(endpoint)
+			continue
+		}
+
+		contents, err := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil || response.StatusCode != http.StatusOK {
+			continue
+		}
+
+		if err := json.Unmarshal(contents, &leader); err == nil {
+			p.setLeader(leader.Leader)
+		}
+		return
+	}
+}
+
+func send(conf *configuration.Configuration, client *http.Client, endpoint string, method string, path string, body []byte, accept string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, endpoint+path, bytes.NewBuffer(body))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Add("Accept", accept)
+		req.Header.Add("Content-Type", "application/json")
+		if conf.ServiceAccount.Enabled() {
+			if err := dcos.Authorize(req, conf.ServiceAccount); err != nil {
+				return nil, err
+			}
+		} else if len(conf.Marathon.User) > 0 && len(conf.Marathon.Password) > 0 {
+			req.SetBasicAuth(conf.Marathon.User, conf.Marathon.Password)
+		}
+
+		response, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		// the service account logs in again when its token is rejected
+		if response.StatusCode != http.StatusUnauthorized || !conf.ServiceAccount.Enabled() || attempt > 0 {
+			return response, nil
+		}
+
+		response.Body.Close()
+		dcos.Invalidate(conf.ServiceAccount)
+	}
+}
+
+// do sends the request to Marathon with the configured timeout, retrying
+// idempotent requests with backoff when every endpoint fails
+func do(conf *configuration.Configuration, method string, path string, body []byte) (*http.Response, error) {
+	if len(poolFor(conf).endpoints) == 0 {
+		return nil, errNoEndpoints
+	}
+
+	client, err := httpclient.NewWithTimeout(conf.Marathon.TLS, conf.HTTP)
+	if err != nil {
+		return nil, err
+	}
+
+	return httpclient.Retry(method, conf.HTTP, func() (*http.Response, error) {
+		return failover(conf, client, method, path, body, "application/json")
+	})
+}
+
+// stream opens a long lived GET request, without timeout or retries
+func stream(conf *configuration.Configuration, path string) (*http.Response, error) {
+	client, err := httpclient.New(conf.Marathon.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	return failover(conf, client, "GET", path, nil, "text/event-stream")
+}
+
+// failover sends the request to the Marathon endpoints in turn, preferring
+// the leader, until one answers without a connection error or 5xx status.
+// Requests that are not idempotent, such as scaling an app, only go to the
+// leader, or the first healthy endpoint when the leader is not known, and
+// their 5xx responses are returned to the caller.
+func failover(conf *configuration.Configuration, client *http.Client, method string, path string, body []byte, accept string) (*http.Response, error) {
+	pool := poolFor(conf)
+
+	if pool.needsLeader() {
+		pool.discoverLeader(conf, client)
+	}
+
+	endpoints := pool.order()
+	if !httpclient.Idempotent(method) && len(endpoints) > 1 {
+		endpoints = endpoints[:1]
+	}
+
+	err := errNoEndpoints
+	for _, endpoint := range endpoints {
+		var response *http.Response
+		response, err = send(conf, client, endpoint, method, path, body, accept)
+		if err != nil {
+			pool.markFailed(endpoint)
+			continue
+		}
+
+		if response.StatusCode >= 500 {
+			pool.markFailed(endpoint)
+			if !httpclient.Idempotent(method) {
+				return response, nil
+			}
+			response.Body.Close()
+			err = errors.New("Marathon " + endpoint + " responded " + response.Status)
+			continue
+		}
+
+		pool.markHealthy(endpoint)
+		return response, nil
+	}
+
+	return nil, err
+}
+
+// getJSON decodes the response to a GET request into v
+func getJSON(conf *configuration.Configuration, path string, v interface{}) error {
+	response, err := do(conf, "GET", path, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := httpclient.CheckStatus(response); err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(contents, v)
+}
@@ -0,0 +1,90 @@
+package marathon
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailoverOnServerError(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, appsJSON)
+	}))
+	defer healthy.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Marathon.Endpoint = failing.URL + "," + healthy.URL
+
+	apps, err := FetchApps(conf)
+
+	assert.Nil(t, err)
+	assert.Contains(t, apps, "/product/us-east/service/myapp")
+	assert.Equal(t, []string{healthy.URL, failing.URL}, poolFor(conf).order())
+}
+
+func TestFailoverOnConnectionError(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, tasksJSON)
+	}))
+	defer healthy.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Marathon.Endpoint = down.URL + ", " + healthy.URL
+
+	tasks, err := FetchTasks(conf)
+
+	assert.Nil(t, err)
+	assert.Len(t, tasks, 2)
+}
+
+func TestPrefersLeader(t *testing.T) {
+	var leaderURL string
+	var followerCalls, leaderCalls int
+
+	follower := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/leader" {
+			fmt.Fprintf(w, `{"leader": "%s"}`, strings.TrimPrefix(leaderURL, "http://"))
+			return
+		}
+		followerCalls++
+		fmt.Fprintln(w, deploymentsJSON)
+	}))
+	defer follower.Close()
+
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaderCalls++
+		fmt.Fprintln(w, deploymentsJSON)
+	}))
+	defer leader.Close()
+	leaderURL = leader.URL
+
+	conf := &configuration.Configuration{}
+	conf.Marathon.Endpoint = follower.URL + "," + leader.URL
+
+	_, err := FetchDeployments(conf)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, followerCalls)
+	assert.Equal(t, 1, leaderCalls)
+}
+
+func TestNoEndpoints(t *testing.T) {
+	conf := &configuration.Configuration{}
+
+	_, err := FetchApps(conf)
+
+	assert.Equal(t, errNoEndpoints, err)
+}
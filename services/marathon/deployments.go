@@ -0,0 +1,29 @@
+package marathon
+
+import "github.com/rossmerr/marathon-autoscale/configuration"
+
+// Deployment in progress on Marathon
+type Deployment struct {
+	ID           string   `json:"id"`
+	Version      string   `json:"version"`
+	AffectedApps []string `json:"affectedApps"`
+	CurrentStep  int      `json:"currentStep"`
+	TotalSteps   int      `json:"totalSteps"`
+}
+
+func FetchDeployments(conf *configuration.Configuration) (map[string]Deployment, error) {
+	var deployments []Deployment
+
+	err := getJSON(conf, "/v2/deployments", &deployments)
+	if err != nil {
+		return nil, err
+	}
+
+	deploymentsByID := map[string]Deployment{}
+
+	for _, deployment := range deployments {
+		deploymentsByID[deployment.ID] = deployment
+	}
+
+	return deploymentsByID, nil
+}
@@ -0,0 +1,423 @@
+package marathon
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+)
+
+// errEventStreamUnavailable is returned when Marathon does not serve /v2/events
+var errEventStreamUnavailable = errors.New("Marathon event stream unavailable")
+
+// reconnect delays of the event stream, doubled after every failed attempt
+var minReconnectDelay = time.Second
+var maxReconnectDelay = time.Minute
+
+// task states after which Marathon no longer lists the task
+var terminalTaskStates = map[string]bool{
+	"TASK_FINISHED":         true,
+	"TASK_FAILED":           true,
+	"TASK_KILLED":           true,
+	"TASK_LOST":             true,
+	"TASK_ERROR":            true,
+	"TASK_DROPPED":          true,
+	"TASK_GONE":             true,
+	"TASK_GONE_BY_OPERATOR": true,
+	"TASK_UNKNOWN":          true,
+}
+
+type statusUpdateEvent struct {
+	AppID      string `json:"appId"`
+	TaskID     string `json:"taskId"`
+	TaskStatus string `json:"taskStatus"`
+	Host       string `json:"host"`
+	Ports      []int  `json:"ports"`
+	Version    string `json:"version"`
+	Timestamp  string `json:"timestamp"`
+}
+
+type healthStatusChangedEvent struct {
+	AppID  string `json:"appId"`
+	TaskID string `json:"taskId"`
+	Alive  bool   `json:"alive"`
+}
+
+type apiPostEvent struct {
+	AppDefinition App `json:"appDefinition"`
+}
+
+type appTerminatedEvent struct {
+	AppID string `json:"appId"`
+}
+
+type deploymentEvent struct {
+	ID string `json:"id"`
+}
+
+// Mirror keeps an in-memory copy of the Marathon apps, tasks, groups and
+// deployments up to date from the /v2/events Server-Sent Events stream, with
+// periodic full resyncs
+type Mirror struct {
+	conf        *configuration.Configuration
+	mu          sync.RWMutex
+	apps        map[string]App
+	tasks       map[string]Task
+	groups      Group
+	deployments map[string]Deployment
+	connected   bool
+	synced      bool
+}
+
+func NewMirror(conf *configuration.Configuration) *Mirror {
+	return &Mirror{conf: conf, apps: map[string]App{}, tasks: map[string]Task{}, deployments: map[string]Deployment{}}
+}
+
+// Snapshot returns copies of the mirrored apps and tasks. ok is false while
+// the event stream is not connected, in which case callers should poll.
+func (m *Mirror) Snapshot() (map[string]App, map[string]Task, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.connected || !m.synced {
+		return nil, nil, false
+	}
+
+	apps := make(map[string]App, len(m.apps))
+	for id, app := range m.apps {
+		apps[id] = app
+	}
+
+	tasks := make(map[string]Task, len(m.tasks))
+	for id, task := range m.tasks {
+		tasks[id] = task
+	}
+
+	return apps, tasks, true
+}
+
+// Groups returns the mirrored group hierarchy, with ok false while the event
+// stream is not connected
+func (m *Mirror) Groups() (Group, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.connected || !m.synced {
+		return Group{}, false
+	}
+
+	return m.groups, true
+}
+
+// Deployments returns a copy of the mirrored deployments in progress, with ok
+// false while the event stream is not connected
+func (m *Mirror) Deployments() (map[string]Deployment, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.connected || !m.synced {
+		return nil, false
+	}
+
+	deployments := make(map[string]Deployment, len(m.deployments))
+	for id, deployment := range m.deployments {
+		deployments[id] = deployment
+	}
+
+	return deployments, true
+}
+
+// Run keeps the mirror up to date, reconnecting to the event stream whenever
+// it drops, until stop is closed
+func (m *Mirror) Run(stop <-chan struct{}) {
+	go m.resyncEvery(stop)
+
+	delay := minReconnectDelay
+	for {
+		connected, err := m.stream(stop)
+		m.setConnected(false)
+
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		if err != nil {
+			log.Printf("Marathon event stream: %s", err)
+		}
+
+		if connected {
+			delay = minReconnectDelay
+		}
+
+		time.Sleep(delay)
+
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+func (m *Mirror) resyncEvery(stop <-chan struct{}) {
+	ticker := time.NewTicker(m.conf.Marathon.ResyncPeriod())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := m.resync(); err != nil {
+				log.Printf("Marathon resync: %s", err)
+			}
+		}
+	}
+}
+
+// resync replaces the mirrored apps, tasks, groups and deployments with a
+// full fetch from Marathon
+func (m *Mirror) resync() error {
+	apps, err := FetchApps(m.conf)
+	if err != nil {
+		return err
+	}
+
+	tasks, err := FetchTasks(m.conf)
+	if err != nil {
+		return err
+	}
+
+	groups, err := FetchGroups(m.conf)
+	if err != nil {
+		return err
+	}
+
+	deployments, err := FetchDeployments(m.conf)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.apps = apps
+	m.tasks = tasks
+	m.groups = groups
+	m.deployments = deployments
+	m.synced = true
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *Mirror) setConnected(connected bool) {
+	m.mu.Lock()
+	m.connected = connected
+	m.mu.Unlock()
+}
+
+// stream reads the event stream until it ends, returning whether it connected
+func (m *Mirror) stream(stop <-chan struct{}) (bool, error) {
+	response, err := stream(m.conf, "/v2/events")
+
+	if err != nil {
+		return false, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK || !strings.HasPrefix(response.Header.Get("Content-Type"), "text/event-stream") {
+		return false, errEventStreamUnavailable
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			response.Body.Close()
+		case <-done:
+		}
+	}()
+
+	// events missed while disconnected are recovered by a full resync
+	if err := m.resync(); err != nil {
+		return false, err
+	}
+	m.setConnected(true)
+
+	reader := bufio.NewReader(response.Body)
+	var eventType string
+	var data []string
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return true, nil
+			}
+			return true, err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case len(line) == 0:
+			if len(data) > 0 {
+				m.handle(eventType, []byte(strings.Join(data, "\n")))
+			}
+			eventType, data = "", nil
+		case strings.HasPrefix(line, "event:"):
+			eventType = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+}
+
+// handle applies a single event to the mirror
+func (m *Mirror) handle(eventType string, data []byte) {
+	switch eventType {
+	case "status_update_event":
+		var event statusUpdateEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			log.Printf("Error decoding %s: %s", eventType, err)
+			return
+		}
+		m.updateTask(event)
+
+	case "health_status_changed_event":
+		var event healthStatusChangedEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			log.Printf("Error decoding %s: %s", eventType, err)
+			return
+		}
+		m.updateHealth(event)
+
+	case "api_post_event":
+		var event apiPostEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			log.Printf("Error decoding %s: %s", eventType, err)
+			return
+		}
+		m.mu.Lock()
+		m.apps[event.AppDefinition.ID] = event.AppDefinition
+		m.mu.Unlock()
+
+	case "app_terminated_event":
+		var event appTerminatedEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			log.Printf("Error decoding %s: %s", eventType, err)
+			return
+		}
+		m.removeApp(event.AppID)
+
+	case "deployment_info":
+		// the deployment plan only lists the affected apps through its steps
+		deployments, err := FetchDeployments(m.conf)
+		if err != nil {
+			log.Printf("Error fetching deployments after %s: %s", eventType, err)
+			return
+		}
+		m.mu.Lock()
+		m.deployments = deployments
+		m.mu.Unlock()
+
+	case "deployment_success", "deployment_failed":
+		var event deploymentEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			log.Printf("Error decoding %s: %s", eventType, err)
+			return
+		}
+
+		// instance counts and app definitions change with deployments
+		apps, err := FetchApps(m.conf)
+		if err != nil {
+			log.Printf("Error fetching apps after %s: %s", eventType, err)
+			return
+		}
+		m.mu.Lock()
+		m.apps = apps
+		delete(m.deployments, event.ID)
+		m.mu.Unlock()
+
+	case "group_change_success":
+		groups, err := FetchGroups(m.conf)
+		if err != nil {
+			log.Printf("Error fetching groups after %s: %s", eventType, err)
+			return
+		}
+		m.mu.Lock()
+		m.groups = groups
+		m.mu.Unlock()
+	}
+}
+
+func (m *Mirror) updateTask(event statusUpdateEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if terminalTaskStates[event.TaskStatus] {
+		delete(m.tasks, event.TaskID)
+		return
+	}
+
+	task, ok := m.tasks[event.TaskID]
+	if !ok {
+		task = Task{AppID: event.AppID, ID: event.TaskID, StagedAt: event.Timestamp}
+	}
+
+	task.Host = event.Host
+	task.Ports = event.Ports
+	task.Version = event.Version
+
+	if event.TaskStatus == "TASK_RUNNING" && len(task.StartedAt) == 0 {
+		task.StartedAt = event.Timestamp
+	}
+
+	m.tasks[event.TaskID] = task
+}
+
+func (m *Mirror) updateHealth(event healthStatusChangedEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	task, ok := m.tasks[event.TaskID]
+	if !ok {
+		return
+	}
+
+	if len(task.HealthCheckResults) == 0 {
+		task.HealthCheckResults = []HealthCheckResult{{TaskID: event.TaskID}}
+	} else {
+		task.HealthCheckResults = append([]HealthCheckResult{}, task.HealthCheckResults...)
+	}
+
+	for i := range task.HealthCheckResults {
+		task.HealthCheckResults[i].Alive = event.Alive
+		if event.Alive {
+			task.HealthCheckResults[i].ConsecutiveFailures = 0
+		}
+	}
+
+	m.tasks[event.TaskID] = task
+}
+
+func (m *Mirror) removeApp(appID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.apps, appID)
+	for id, task := range m.tasks {
+		if task.AppID == appID {
+			delete(m.tasks, id)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package marathon
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/stretchr/testify/assert"
+)
+
+const statusUpdateEventStream = `event: status_update_event
+data: {"eventType": "status_update_event", "slaveId": "20140909-054127-177048842-5050-1494-0", "taskId": "my-app_0-1396592784349", "taskStatus": "TASK_RUNNING", "appId": "/my-app", "host": "slave-1234.acme.org", "ports": [31372], "version": "2014-04-04T06:26:23.051Z", "timestamp": "2014-04-04T06:26:25.051Z"}
+
+`
+
+const killedEventStream = `event: status_update_event
+data: {"eventType": "status_update_event", "taskId": "bridged-webapp.eb76c51f-4b4a-11e4-ae49-56847afe9799", "taskStatus": "TASK_KILLED", "appId": "/bridged-webapp"}
+
+`
+
+const deploymentSuccessEventStream = `event: deployment_success
+data: {"eventType": "deployment_success", "id": "867ed450-f6a8-4d33-9b0e-e11c5513990b", "timestamp": "2014-04-04T06:27:25.051Z"}
+
+`
+
+// waitFor polls the condition until it holds or the test times out
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMirror(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/apps":
+			fmt.Fprintln(w, appsJSON)
+		case "/v2/tasks":
+			fmt.Fprintln(w, tasksJSON)
+		case "/v2/groups":
+			fmt.Fprintln(w, groupsJSON)
+		case "/v2/deployments":
+			fmt.Fprintln(w, deploymentsJSON)
+		case "/v2/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, statusUpdateEventStream)
+			fmt.Fprint(w, killedEventStream)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer ts.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Marathon.Endpoint = ts.URL
+	mirror := NewMirror(conf)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go mirror.Run(stop)
+
+	waitFor(t, func() bool {
+		_, tasks, ok := mirror.Snapshot()
+		_, running := tasks["my-app_0-1396592784349"]
+		return ok && running
+	})
+
+	apps, tasks, _ := mirror.Snapshot()
+	assert.Contains(t, apps, "/product/us-east/service/myapp")
+	assert.Equal(t, "2014-04-04T06:26:25.051Z", tasks["my-app_0-1396592784349"].StartedAt)
+	assert.NotContains(t, tasks, "bridged-webapp.eb76c51f-4b4a-11e4-ae49-56847afe9799")
+	assert.Contains(t, tasks, "bridged-webapp.ef0b5d91-4b4a-11e4-ae49-56847afe9799")
+}
+
+func TestClientServesGroupsAndDeploymentsFromMirror(t *testing.T) {
+	polled := map[string]int{}
+	var mu sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		polled[r.URL.Path]++
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/v2/apps":
+			fmt.Fprintln(w, appsJSON)
+		case "/v2/tasks":
+			fmt.Fprintln(w, tasksJSON)
+		case "/v2/groups":
+			fmt.Fprintln(w, groupsJSON)
+		case "/v2/deployments":
+			fmt.Fprintln(w, deploymentsJSON)
+		case "/v2/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, deploymentSuccessEventStream)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer ts.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Marathon.Endpoint = ts.URL
+	client := &Client{conf: conf, mirror: NewMirror(conf)}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go client.mirror.Run(stop)
+
+	waitFor(t, func() bool {
+		deployments, ok := client.mirror.Deployments()
+		return ok && len(deployments) == 0
+	})
+
+	for i := 0; i < 3; i++ {
+		groups, err := client.FetchGroups()
+		assert.Nil(t, err)
+		assert.Equal(t, "/", groups.ID)
+
+		deployments, err := client.FetchDeployments()
+		assert.Nil(t, err)
+		assert.Empty(t, deployments)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, polled["/v2/groups"])
+	assert.Equal(t, 1, polled["/v2/deployments"])
+}
+
+func TestMirrorUnavailable(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Marathon.Endpoint = ts.URL
+	mirror := NewMirror(conf)
+
+	connected, err := mirror.stream(nil)
+
+	assert.False(t, connected)
+	assert.Equal(t, errEventStreamUnavailable, err)
+
+	_, _, ok := mirror.Snapshot()
+	assert.False(t, ok)
+}
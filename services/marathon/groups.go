@@ -0,0 +1,42 @@
+package marathon
+
+import (
+	"github.com/rossmerr/marathon-autoscale/configuration"
+)
+
+// Group in the Marathon group hierarchy, e.g. /product/us-east. Marathon
+// groups carry no labels; the autoscale defaults of a group are declared by
+// the configuration file policies keyed by group ID.
+type Group struct {
+	ID     string  `json:"id"`
+	Apps   []App   `json:"apps"`
+	Groups []Group `json:"groups"`
+}
+
+func FetchGroups(conf *configuration.Configuration) (Group, error) {
+	var root Group
+
+	err := getJSON(conf, "/v2/groups", &root)
+
+	return root, err
+}
+
+// AppGroups returns, by app ID, the IDs of the groups enclosing each app,
+// outermost first
+func (g Group) AppGroups() map[string][]string {
+	groupsByID := map[string][]string{}
+	g.appGroups(nil, groupsByID)
+	return groupsByID
+}
+
+func (g Group) appGroups(parents []string, groupsByID map[string][]string) {
+	groupIDs := append(append([]string{}, parents...), g.ID)
+
+	for _, app := range g.Apps {
+		groupsByID[app.ID] = groupIDs
+	}
+
+	for _, group := range g.Groups {
+		group.appGroups(groupIDs, groupsByID)
+	}
+}
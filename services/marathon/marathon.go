@@ -1,12 +1,11 @@
 package marathon
 
 import (
-	"bytes"
 	"encoding/json"
 	"io/ioutil"
-	"math"
-	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/rossmerr/marathon-autoscale/configuration"
 )
@@ -19,6 +18,10 @@ type App struct {
 	Env          map[string]string `json:"env"`
 	Labels       map[string]string `json:"labels"`
 	Instances    int               `json:"instances"`
+	CPUs         float64           `json:"cpus"`
+	Mem          float64           `json:"mem"`
+	Disk         float64           `json:"disk"`
+	GPUs         float64           `json:"gpus"`
 	TasksRunning int               `json:"tasksRunning"`
 	TasksStaged  int               `json:"tasksStaged"`
 }
@@ -33,6 +36,7 @@ type Task struct {
 	AppID              string
 	ID                 string
 	Host               string
+	SlaveID            string
 	Ports              []int
 	ServicePorts       []int
 	StartedAt          string
@@ -41,6 +45,34 @@ type Task struct {
 	HealthCheckResults []HealthCheckResult
 }
 
+// Healthy is true when the task has started and passes all of its app's health checks
+func (t Task) Healthy(app App) bool {
+	if len(t.StartedAt) == 0 {
+		return false
+	}
+
+	if len(t.HealthCheckResults) < len(app.HealthChecks) {
+		return false
+	}
+
+	for _, result := range t.HealthCheckResults {
+		if !result.Alive {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Started returns the time the task started running, false while it is staging
+func (t Task) Started() (time.Time, bool) {
+	startedAt, err := time.Parse(time.RFC3339, t.StartedAt)
+	if err != nil {
+		return startedAt, false
+	}
+	return startedAt, true
+}
+
 type HealthCheckResult struct {
 	Alive               bool     `json:"Alive"`
 	ConsecutiveFailures int      `json:"consecutiveFailures"`
@@ -65,28 +97,9 @@ type HealthCheck struct {
 }
 
 func FetchApps(conf *configuration.Configuration) (map[string]App, error) {
-	client := &http.Client{}
-	req, _ := http.NewRequest("GET", conf.Marathon.Endpoint+"/v2/apps", nil)
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	if len(conf.Marathon.User) > 0 && len(conf.Marathon.Password) > 0 {
-		req.SetBasicAuth(conf.Marathon.User, conf.Marathon.Password)
-	}
-	response, err := client.Do(req)
-
-	if err != nil {
-		return nil, err
-	}
-
-	defer response.Body.Close()
 	var appResponse apps
 
-	contents, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	err = json.Unmarshal(contents, &appResponse)
+	err := getJSON(conf, "/v2/apps", &appResponse)
 	if err != nil {
 		return nil, err
 	}
@@ -101,28 +114,9 @@ func FetchApps(conf *configuration.Configuration) (map[string]App, error) {
 }
 
 func FetchTasks(conf *configuration.Configuration) (map[string]Task, error) {
-	client := &http.Client{}
-	req, _ := http.NewRequest("GET", conf.Marathon.Endpoint+"/v2/tasks", nil)
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	if len(conf.Marathon.User) > 0 && len(conf.Marathon.Password) > 0 {
-		req.SetBasicAuth(conf.Marathon.User, conf.Marathon.Password)
-	}
-	response, err := client.Do(req)
-
 	var tasks tasks
 
-	if err != nil {
-		return nil, err
-	}
-
-	contents, err := ioutil.ReadAll(response.Body)
-	defer response.Body.Close()
-	if err != nil {
-		return nil, err
-	}
-
-	err = json.Unmarshal(contents, &tasks)
+	err := getJSON(conf, "/v2/tasks", &tasks)
 	if err != nil {
 		return nil, err
 	}
@@ -140,38 +134,47 @@ func (app App) FetchDetails() (map[string]Task, error) {
 	return nil, nil
 }
 
-func (app App) ScaleApp(conf *configuration.Configuration, marathonApp string) error {
+// ScaleApp sets the number of instances of the app, returning the ID of the
+// deployment Marathon started for it
+func (app App) ScaleApp(conf *configuration.Configuration, instances int) (string, error) {
+	return app.scale(conf, instances, false)
+}
 
-	autoscaleMultiplier, err := strconv.Atoi(app.Labels["autoscaleMultiplier"])
-	if err != nil {
-		return err
+// ForceScaleApp sets the number of instances of the app even while a
+// deployment holds its lock, which Marathon cancels
+func (app App) ForceScaleApp(conf *configuration.Configuration, instances int) (string, error) {
+	return app.scale(conf, instances, true)
+}
+
+func (app App) scale(conf *configuration.Configuration, instances int, force bool) (string, error) {
+	path := "/v2/apps/" + strings.TrimPrefix(app.ID, "/")
+	if force {
+		path += "?force=true"
 	}
 
-	maxInstances, err := strconv.Atoi(app.Labels["maxInstances"])
+	var jsonStr = []byte(`{"instances": ` + strconv.Itoa(instances) + `}`)
+	response, err := do(conf, "PUT", path, jsonStr)
+
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	targetInstancesFloat := float64(app.Instances * autoscaleMultiplier)
-	targetInstances := int(math.Ceil(targetInstancesFloat))
+	defer response.Body.Close()
 
-	if targetInstances > maxInstances {
-		targetInstances = maxInstances
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", err
 	}
 
-	client := &http.Client{}
-	var jsonStr = []byte(`{"instances": ` + strconv.Itoa(targetInstances) + `}`)
-	req, _ := http.NewRequest("PUT", conf.Marathon.Endpoint+"/v2/apps/"+app.ID, bytes.NewBuffer(jsonStr))
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	if len(conf.Marathon.User) > 0 && len(conf.Marathon.Password) > 0 {
-		req.SetBasicAuth(conf.Marathon.User, conf.Marathon.Password)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return "", newScaleError(app.ID, response.StatusCode, contents)
 	}
-	_, err = client.Do(req)
 
+	var result scaleResult
+	err = json.Unmarshal(contents, &result)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	return result.DeploymentID, nil
 }
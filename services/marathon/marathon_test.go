@@ -138,3 +138,86 @@ func TestFetchTasks(t *testing.T) {
 		assert.Equal(t, true, task.HealthCheckResults[0].Alive)
 	}
 }
+
+const groupsJSON = `{
+    "id": "/",
+    "apps": [],
+    "groups": [
+        {
+            "id": "/product",
+            "apps": [],
+            "groups": [
+                {
+                    "id": "/product/us-east",
+                    "apps": [
+                        {
+                            "id": "/product/us-east/service",
+                            "instances": 2,
+                            "labels": {
+                                "maxCPUTime": "80"
+                            }
+                        }
+                    ],
+                    "groups": []
+                }
+            ]
+        }
+    ]
+}`
+
+func TestFetchGroups(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, groupsJSON)
+	}))
+	defer ts.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Marathon.Endpoint = ts.URL
+	root, err := FetchGroups(conf)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, "/", root.ID)
+
+	groupIDs := root.AppGroups()["/product/us-east/service"]
+	assert.Equal(t, []string{"/", "/product", "/product/us-east"}, groupIDs)
+}
+
+const deploymentsJSON = `[
+    {
+        "affectedApps": [
+            "/product/us-east/service/myapp"
+        ],
+        "currentActions": [
+            {
+                "action": "ScaleApplication",
+                "app": "/product/us-east/service/myapp"
+            }
+        ],
+        "currentStep": 1,
+        "id": "867ed450-f6a8-4d33-9b0e-e11c5513990b",
+        "totalSteps": 1,
+        "version": "2014-08-26T08:18:03.595Z"
+    }
+]`
+
+func TestFetchDeployments(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, deploymentsJSON)
+	}))
+	defer ts.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Marathon.Endpoint = ts.URL
+	deployments, err := FetchDeployments(conf)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	deployment := deployments["867ed450-f6a8-4d33-9b0e-e11c5513990b"]
+	assert.Equal(t, []string{"/product/us-east/service/myapp"}, deployment.AffectedApps)
+	assert.Equal(t, 1, deployment.TotalSteps)
+}
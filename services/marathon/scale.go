@@ -0,0 +1,61 @@
+package marathon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type scaleResult struct {
+	Version      string `json:"version"`
+	DeploymentID string `json:"deploymentId"`
+}
+
+// ScaleError is returned when Marathon rejects a scale request
+type ScaleError struct {
+	AppID      string
+	StatusCode int
+	Message    string
+	// deployments holding the lock on the app, when StatusCode is 409
+	Deployments []string
+}
+
+func (e *ScaleError) Error() string {
+	return fmt.Sprintf("Scaling %s failed with status %d: %s", e.AppID, e.StatusCode, e.Message)
+}
+
+// Unauthorized is true when the Marathon credentials were rejected
+func (e *ScaleError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+// Locked is true when the app is locked by another deployment
+func (e *ScaleError) Locked() bool {
+	return e.StatusCode == http.StatusConflict
+}
+
+// Invalid is true when Marathon rejected the new app definition
+func (e *ScaleError) Invalid() bool {
+	return e.StatusCode == http.StatusUnprocessableEntity || e.StatusCode == http.StatusBadRequest
+}
+
+func newScaleError(appID string, statusCode int, contents []byte) *ScaleError {
+	var body struct {
+		Message     string `json:"message"`
+		Deployments []struct {
+			ID string `json:"id"`
+		} `json:"deployments"`
+	}
+
+	scaleErr := &ScaleError{AppID: appID, StatusCode: statusCode, Message: http.StatusText(statusCode)}
+
+	if err := json.Unmarshal(contents, &body); err == nil && len(body.Message) > 0 {
+		scaleErr.Message = body.Message
+	}
+
+	for _, deployment := range body.Deployments {
+		scaleErr.Deployments = append(scaleErr.Deployments, deployment.ID)
+	}
+
+	return scaleErr
+}
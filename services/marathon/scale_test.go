@@ -0,0 +1,108 @@
+package marathon
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScaleApp(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PUT", r.Method)
+		assert.Equal(t, "/v2/apps/product/us-east/service/myapp", r.URL.Path)
+		fmt.Fprintln(w, `{"version": "2014-08-26T07:37:50.462Z", "deploymentId": "83b215a6-4e26-4e44-9333-5c385eda6438"}`)
+	}))
+	defer ts.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Marathon.Endpoint = ts.URL
+	app := App{ID: "/product/us-east/service/myapp", Instances: 2}
+
+	deploymentID, err := app.ScaleApp(conf, 3)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "83b215a6-4e26-4e44-9333-5c385eda6438", deploymentID)
+}
+
+func TestScaleAppLocked(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintln(w, `{"message": "App is locked by one or more deployments.", "deployments": [{"id": "97c136bf-5a28-4821-9d94-480d9fbb01c8"}]}`)
+	}))
+	defer ts.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Marathon.Endpoint = ts.URL
+	app := App{ID: "/product/us-east/service/myapp", Instances: 2}
+
+	_, err := app.ScaleApp(conf, 3)
+
+	scaleErr, ok := err.(*ScaleError)
+	assert.True(t, ok)
+	assert.True(t, scaleErr.Locked())
+	assert.Equal(t, "App is locked by one or more deployments.", scaleErr.Message)
+	assert.Equal(t, []string{"97c136bf-5a28-4821-9d94-480d9fbb01c8"}, scaleErr.Deployments)
+}
+
+func TestForceScaleApp(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("force") != "true" {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprintln(w, `{"message": "App is locked by one or more deployments."}`)
+			return
+		}
+		fmt.Fprintln(w, `{"version": "2014-08-26T07:37:50.462Z", "deploymentId": "5ed4c0c5-9ff8-4a6f-a0cd-f57f59a34b43"}`)
+	}))
+	defer ts.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Marathon.Endpoint = ts.URL
+	app := App{ID: "/myapp", Instances: 2}
+
+	_, err := app.ScaleApp(conf, 1)
+	assert.True(t, err.(*ScaleError).Locked())
+
+	deploymentID, err := app.ForceScaleApp(conf, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "5ed4c0c5-9ff8-4a6f-a0cd-f57f59a34b43", deploymentID)
+}
+
+func TestScaleAppServerError(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Marathon.Endpoint = ts.URL
+
+	_, err := App{ID: "/myapp"}.ScaleApp(conf, 3)
+
+	scaleErr, ok := err.(*ScaleError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusServiceUnavailable, scaleErr.StatusCode)
+	assert.Equal(t, 1, calls)
+}
+
+func TestScaleAppUnauthorized(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Marathon.Endpoint = ts.URL
+	app := App{ID: "/product/us-east/service/myapp", Instances: 2}
+
+	_, err := app.ScaleApp(conf, 3)
+
+	scaleErr, ok := err.(*ScaleError)
+	assert.True(t, ok)
+	assert.True(t, scaleErr.Unauthorized())
+}
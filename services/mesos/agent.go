@@ -0,0 +1,47 @@
+package mesos
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/rossmerr/marathon-autoscale/services/httpclient"
+)
+
+// doAgent sends a request that only reads state to the agent, either directly
+// at the address in its PID or, when an agent proxy is configured, through its
+// /agent/<id>/ path so only the proxy needs to be reachable
+func doAgent(conf *configuration.Configuration, agent Slave, method string, path string, body []byte) (*http.Response, error) {
+	if len(conf.Mesos.AgentProxy) > 0 {
+		return doAgentProxy(conf, agent, method, path, body)
+	}
+
+	client, err := httpclient.NewWithTimeout(conf.Mesos.AgentTLSConfig(), conf.HTTP)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := agent.Endpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	return httpclient.RetryRead(conf.HTTP, func() (*http.Response, error) {
+		return send(client, conf, method, conf.Mesos.AgentURL(endpoint)+path, body, conf.Mesos.AgentCredentials())
+	})
+}
+
+// doAgentProxy sends the request to the agent through the agent proxy, with
+// the TLS settings and credentials of the masters it sits in front of
+func doAgentProxy(conf *configuration.Configuration, agent Slave, method string, path string, body []byte) (*http.Response, error) {
+	client, err := httpclient.NewWithTimeout(conf.Mesos.TLS, conf.HTTP)
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimRight(conf.Mesos.AgentProxy, "/") + "/agent/" + agent.ID + path
+
+	return httpclient.RetryRead(conf.HTTP, func() (*http.Response, error) {
+		return send(client, conf, method, url, body, conf.Mesos.MasterCredentials())
+	})
+}
@@ -0,0 +1,48 @@
+package mesos
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchAgentStatisticsThroughProxy(t *testing.T) {
+	master := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("agent request %s sent to the master", r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer master.Close()
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/agent/aa53014e-04cc-49e7-975d-60c635a70c7f-S29/monitor/statistics":
+			fmt.Fprintln(w, statisticsJSON)
+		case "/agent/aa53014e-04cc-49e7-975d-60c635a70c7f-S29/api/v1":
+			fmt.Fprintln(w, getContainersJSON)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer proxy.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Mesos.Endpoint = master.URL + "/mesos"
+	conf.Mesos.AgentProxy = proxy.URL + "/"
+
+	// the agent address in the PID is not reachable
+	agent := Slave{ID: "aa53014e-04cc-49e7-975d-60c635a70c7f-S29", PID: "slave(1)@192.0.2.1:5051"}
+
+	resources, err := NewClient(conf).FetchAgentStatistics(agent)
+	assert.NoError(t, err)
+	assert.Len(t, resources, 1)
+	assert.Equal(t, "aa53014e-04cc-49e7-975d-60c635a70c7f-0001", resources[0].FrameworkID)
+
+	resources, err = NewOperatorClient(conf).FetchAgentStatistics(agent)
+	assert.NoError(t, err)
+	assert.Len(t, resources, 1)
+	assert.Equal(t, "8f7c7a35-2e4b-4a3c-9a0b-4bb2e8b1b6a1", resources[0].ContainerID)
+}
@@ -0,0 +1,24 @@
+package mesos
+
+import "github.com/rossmerr/marathon-autoscale/configuration"
+
+// Client of the configured Mesos masters and their agents
+type Client struct {
+	conf *configuration.Configuration
+}
+
+func NewClient(conf *configuration.Configuration) *Client {
+	return &Client{conf: conf}
+}
+
+func (c *Client) FetchAgents() (map[string]Slave, error) {
+	return FetchAgents(c.conf)
+}
+
+func (c *Client) FetchAgentStatistics(agent Slave) ([]Resource, error) {
+	return agent.FetchAgentStatistics(c.conf)
+}
+
+func (c *Client) FetchTasks() (map[string]Task, error) {
+	return FetchTasks(c.conf)
+}
@@ -0,0 +1,56 @@
+package mesos
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/rossmerr/marathon-autoscale/services/dcos"
+)
+
+// authorize adds the credentials to the request: the DC/OS service account
+// token when one is configured, otherwise a bearer token or basic auth
+func authorize(req *http.Request, conf *configuration.Configuration, credentials configuration.Credentials) error {
+	if conf.ServiceAccount.Enabled() {
+		return dcos.Authorize(req, conf.ServiceAccount)
+	}
+
+	if len(credentials.Token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+credentials.Token)
+		return nil
+	}
+
+	if len(credentials.User) > 0 && len(credentials.Password) > 0 {
+		req.SetBasicAuth(credentials.User, credentials.Password)
+	}
+	return nil
+}
+
+// send sends an authorized request, logging the service account in again and
+// retrying once when its token is rejected
+func send(client *http.Client, conf *configuration.Configuration, method string, url string, body []byte, credentials configuration.Credentials) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Add("Accept", "application/json")
+		req.Header.Add("Content-Type", "application/json")
+		if err := authorize(req, conf, credentials); err != nil {
+			return nil, err
+		}
+
+		response, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if response.StatusCode != http.StatusUnauthorized || !conf.ServiceAccount.Enabled() || attempt > 0 {
+			return response, nil
+		}
+
+		response.Body.Close()
+		dcos.Invalidate(conf.ServiceAccount)
+	}
+}
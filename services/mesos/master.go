@@ -0,0 +1,173 @@
+package mesos
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/rossmerr/marathon-autoscale/services/httpclient"
+)
+
+// errNoMasters is returned when no Mesos master endpoint is configured
+var errNoMasters = errors.New("No Mesos master endpoint configured")
+
+// leaders caches the leading master by configured endpoint list
+var leaders = map[string]string{}
+var leadersMu sync.Mutex
+
+func masterEndpoints(conf *configuration.Configuration) []string {
+	endpoints := []string{}
+	for _, endpoint := range conf.Mesos.Endpoints() {
+		endpoint = strings.TrimRight(strings.TrimSpace(endpoint), "/")
+		if len(endpoint) > 0 {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+func cachedLeader(conf *configuration.Configuration) string {
+	leadersMu.Lock()
+	defer leadersMu.Unlock()
+
+	return leaders[conf.Mesos.Endpoint]
+}
+
+func setLeader(conf *configuration.Configuration, leader string) {
+	leadersMu.Lock()
+	defer leadersMu.Unlock()
+
+	if len(leader) == 0 {
+		delete(leaders, conf.Mesos.Endpoint)
+		return
+	}
+	leaders[conf.Mesos.Endpoint] = leader
+}
+
+// configuredMaster returns the configured master endpoint, base path included,
+// served at the scheme and host of the URL, or an empty string
+func configuredMaster(conf *configuration.Configuration, u *url.URL) string {
+	for _, endpoint := range masterEndpoints(conf) {
+		base, err := url.Parse(endpoint)
+		if err == nil && base.Scheme == u.Scheme && base.Host == u.Host {
+			return endpoint
+		}
+	}
+	return ""
+}
+
+// discoverLeader asks the masters in turn where /master/redirect points to,
+// returning an empty string when none of them knows the leader. The leader is
+// only taken as a configured endpoint, so a base path such as /mesos is kept;
+// a master redirecting to an address that is not configured, like the
+// internal address of the leader behind a proxy, is kept as the leader itself.
+func discoverLeader(conf *configuration.Configuration) string {
+	masterClient, err := httpclient.NewWithTimeout(conf.Mesos.TLS, conf.HTTP)
+	if err != nil {
+		return ""
+	}
+
+	client := *masterClient
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	for _, endpoint := range masterEndpoints(conf) {
+		response, err := send(&client, conf, "GET", endpoint+"/master/redirect", nil, conf.Mesos.MasterCredentials())
+		if err != nil {
+			continue
+		}
+		response.Body.Close()
+
+		location, err := response.Location()
+		if err != nil {
+			continue
+		}
+
+		base, err := url.Parse(endpoint)
+		if err != nil {
+			continue
+		}
+
+		if leader := configuredMaster(conf, base.ResolveReference(location)); len(leader) > 0 {
+			return leader
+		}
+		return endpoint
+	}
+
+	return ""
+}
+
+// doMaster sends a GET request to the masters with the configured timeout,
+// retrying with backoff when every master fails
+func doMaster(conf *configuration.Configuration, path string) (*http.Response, error) {
+	return doMasterCall(conf, "GET", path, nil)
+}
+
+// doMasterCall sends a request that only reads state to the masters with the
+// configured timeout, retrying with backoff when every master fails
+func doMasterCall(conf *configuration.Configuration, method string, path string, body []byte) (*http.Response, error) {
+	if len(masterEndpoints(conf)) == 0 {
+		return nil, errNoMasters
+	}
+
+	client, err := httpclient.NewWithTimeout(conf.Mesos.TLS, conf.HTTP)
+	if err != nil {
+		return nil, err
+	}
+
+	return httpclient.RetryRead(conf.HTTP, func() (*http.Response, error) {
+		return masters(conf, client, method, path, body)
+	})
+}
+
+// masters sends the request to the leading master, discovering it when it
+// is not cached, and falls back to the configured masters in turn. Requests
+// a former leader redirects to another configured master update the cached
+// leader.
+func masters(conf *configuration.Configuration, client *http.Client, method string, path string, body []byte) (*http.Response, error) {
+	leader := cachedLeader(conf)
+	if len(leader) == 0 {
+		leader = discoverLeader(conf)
+		setLeader(conf, leader)
+	}
+
+	endpoints := []string{}
+	if len(leader) > 0 {
+		endpoints = append(endpoints, leader)
+	}
+	for _, endpoint := range masterEndpoints(conf) {
+		if endpoint != leader {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+
+	err := errNoMasters
+	for _, endpoint := range endpoints {
+		var response *http.Response
+		response, err = send(client, conf, method, endpoint+path, body, conf.Mesos.MasterCredentials())
+		if err != nil || response.StatusCode >= 500 {
+			if err == nil {
+				response.Body.Close()
+				err = errors.New("Mesos master " + endpoint + " responded " + response.Status)
+			}
+			if endpoint == leader {
+				setLeader(conf, "")
+			}
+			continue
+		}
+
+		if endpoint == leader {
+			if final := configuredMaster(conf, response.Request.URL); len(final) > 0 && final != leader {
+				setLeader(conf, final)
+			}
+		}
+
+		return response, nil
+	}
+
+	return nil, err
+}
@@ -0,0 +1,171 @@
+package mesos
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchAgentsFromLeader(t *testing.T) {
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slaves" {
+			fmt.Fprintln(w, slavesJSON)
+		}
+	}))
+	defer leader.Close()
+
+	follower := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/master/redirect" {
+			http.Redirect(w, r, "//"+strings.TrimPrefix(leader.URL, "http://"), http.StatusTemporaryRedirect)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer follower.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Mesos.Endpoint = follower.URL + "," + leader.URL
+
+	slaves, err := FetchAgents(conf)
+
+	assert.Nil(t, err)
+	assert.Len(t, slaves, 1)
+	assert.Equal(t, leader.URL, cachedLeader(conf))
+}
+
+func TestFetchAgentsFromLeaderWithBasePath(t *testing.T) {
+	redirects := 0
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/mesos/slaves" {
+			fmt.Fprintln(w, slavesJSON)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer leader.Close()
+
+	follower := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/mesos/master/redirect" {
+			redirects++
+			http.Redirect(w, r, "//"+strings.TrimPrefix(leader.URL, "http://"), http.StatusTemporaryRedirect)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer follower.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Mesos.Endpoint = follower.URL + "/mesos," + leader.URL + "/mesos/"
+
+	for i := 0; i < 2; i++ {
+		slaves, err := FetchAgents(conf)
+
+		assert.Nil(t, err)
+		assert.Len(t, slaves, 1)
+	}
+	assert.Equal(t, leader.URL+"/mesos", cachedLeader(conf))
+	assert.Equal(t, 1, redirects)
+}
+
+func TestFetchAgentsThroughProxy(t *testing.T) {
+	redirects := 0
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/mesos/slaves":
+			fmt.Fprintln(w, slavesJSON)
+		case "/mesos/master/redirect":
+			redirects++
+			http.Redirect(w, r, "//10.0.0.1:5050", http.StatusTemporaryRedirect)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer proxy.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Mesos.Endpoint = proxy.URL + "/mesos"
+
+	for i := 0; i < 2; i++ {
+		slaves, err := FetchAgents(conf)
+
+		assert.Nil(t, err)
+		assert.Len(t, slaves, 1)
+	}
+	assert.Equal(t, proxy.URL+"/mesos", cachedLeader(conf))
+	assert.Equal(t, 1, redirects)
+}
+
+func TestFetchAgentsLeaderFailover(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+
+	master := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, slavesJSON)
+	}))
+	defer master.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Mesos.Endpoint = down.URL + "," + master.URL
+	setLeader(conf, down.URL)
+
+	slaves, err := FetchAgents(conf)
+
+	assert.Nil(t, err)
+	assert.Len(t, slaves, 1)
+	assert.Equal(t, "", cachedLeader(conf))
+}
+
+func TestFetchAgentsLeaderChanged(t *testing.T) {
+	newLeader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, slavesJSON)
+	}))
+	defer newLeader.Close()
+
+	oldLeader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, newLeader.URL+r.URL.Path, http.StatusTemporaryRedirect)
+	}))
+	defer oldLeader.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Mesos.Endpoint = oldLeader.URL + "," + newLeader.URL
+	setLeader(conf, oldLeader.URL)
+
+	_, err := FetchAgents(conf)
+
+	assert.Nil(t, err)
+	assert.Equal(t, newLeader.URL, cachedLeader(conf))
+}
+
+func TestFetchWithCredentials(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/monitor/statistics" {
+			assert.Equal(t, "Bearer agent-token", r.Header.Get("Authorization"))
+			fmt.Fprintln(w, statisticsJSON)
+			return
+		}
+		user, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "master", user)
+		assert.Equal(t, "secret", password)
+		fmt.Fprintln(w, slavesJSON)
+	}))
+	defer ts.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Mesos.Endpoint = ts.URL
+	conf.Mesos.User = "master"
+	conf.Mesos.Password = "secret"
+	conf.Mesos.Agent.Token = "agent-token"
+
+	_, err := FetchAgents(conf)
+	assert.Nil(t, err)
+
+	slave := Slave{PID: "slave(1)@" + strings.TrimPrefix(ts.URL, "http://")}
+	_, err = slave.FetchAgentStatistics(conf)
+	assert.Nil(t, err)
+}
@@ -4,10 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"io/ioutil"
-	"net/http"
 	"strings"
 
 	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/rossmerr/marathon-autoscale/services/httpclient"
 )
 
 type Resources []Resource
@@ -17,29 +17,81 @@ type Resource struct {
 	ExecutorName string     `json:"executor_name"`
 	FrameworkID  string     `json:"framework_id"`
 	Source       string     `json:"source"`
+	ContainerID  string     `json:"container_id"`
 	Statistics   Statistics `json:"statistics"`
 }
 
+// Statistics of a container as reported by the agent. Fields the isolators of
+// the agent do not collect are left at zero, e.g. the net_* fields need the
+// network port mapping isolator and the disk_* fields the disk/du isolator.
 type Statistics struct {
-	CPUsLimit          float32 `json:"cpus_limit"`
-	CPUsSystemTimeSecs float32 `json:"cpus_system_time_secs"`
-	CPUsUserTimeSecs   float32 `json:"cpus_user_time_secs"`
-	MemLimitBytes      int     `json:"mem_limit_bytes"`
-	MemRssBytes        int     `json:"mem_rss_bytes"`
-	Timestamp          float32 `json:"timestamp"`
+	Timestamp float64 `json:"timestamp"`
+	Processes int     `json:"processes"`
+	Threads   int     `json:"threads"`
+
+	CPUsLimit             float64 `json:"cpus_limit"`
+	CPUsSystemTimeSecs    float64 `json:"cpus_system_time_secs"`
+	CPUsUserTimeSecs      float64 `json:"cpus_user_time_secs"`
+	CPUsNrPeriods         int     `json:"cpus_nr_periods"`
+	CPUsNrThrottled       int     `json:"cpus_nr_throttled"`
+	CPUsThrottledTimeSecs float64 `json:"cpus_throttled_time_secs"`
+
+	MemLimitBytes        int `json:"mem_limit_bytes"`
+	MemSoftLimitBytes    int `json:"mem_soft_limit_bytes"`
+	MemRssBytes          int `json:"mem_rss_bytes"`
+	MemTotalBytes        int `json:"mem_total_bytes"`
+	MemTotalMemswBytes   int `json:"mem_total_memsw_bytes"`
+	MemCacheBytes        int `json:"mem_cache_bytes"`
+	MemFileBytes         int `json:"mem_file_bytes"`
+	MemAnonBytes         int `json:"mem_anon_bytes"`
+	MemMappedFileBytes   int `json:"mem_mapped_file_bytes"`
+	MemSwapBytes         int `json:"mem_swap_bytes"`
+	MemUnevictableBytes  int `json:"mem_unevictable_bytes"`
+	MemLowPressureCount  int `json:"mem_low_pressure_counter"`
+	MemMedPressureCount  int `json:"mem_medium_pressure_counter"`
+	MemCritPressureCount int `json:"mem_critical_pressure_counter"`
+
+	DiskLimitBytes int `json:"disk_limit_bytes"`
+	DiskUsedBytes  int `json:"disk_used_bytes"`
+
+	NetRxPackets int `json:"net_rx_packets"`
+	NetRxBytes   int `json:"net_rx_bytes"`
+	NetRxErrors  int `json:"net_rx_errors"`
+	NetRxDropped int `json:"net_rx_dropped"`
+	NetTxPackets int `json:"net_tx_packets"`
+	NetTxBytes   int `json:"net_tx_bytes"`
+	NetTxErrors  int `json:"net_tx_errors"`
+	NetTxDropped int `json:"net_tx_dropped"`
+
+	// the agent reported the net_* fields, which are zero for an idle task
+	HasNetwork bool `json:"-"`
 }
 
-func (s Slave) FetchAgentStatistics() ([]Resource, error) {
-	client := &http.Client{}
-	endpoint, err := s.Endpoint()
-	req, _ := http.NewRequest("GET", "http://"+endpoint+"/monitor/statistics", nil)
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	response, err := client.Do(req)
+func (s *Statistics) UnmarshalJSON(data []byte) error {
+	type statistics Statistics
+	if err := json.Unmarshal(data, (*statistics)(s)); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	_, s.HasNetwork = fields["net_rx_bytes"]
+	return nil
+}
+
+func (s Slave) FetchAgentStatistics(conf *configuration.Configuration) ([]Resource, error) {
+	response, err := doAgent(conf, s, "GET", "/monitor/statistics", nil)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := httpclient.CheckStatus(response); err != nil {
+		return nil, err
+	}
+
 	defer response.Body.Close()
 	var resources Resources
 
@@ -94,17 +146,27 @@ type SlaveResources struct {
 	CPUS float32 `json:"cpus"`
 }
 
+// Free returns the unreserved resources of the agent not in use by tasks
+func (s Slave) Free() SlaveResources {
+	return SlaveResources{
+		Disk: s.UnReservedResources.Disk - s.UsedResources.Disk,
+		Mem:  s.UnReservedResources.Mem - s.UsedResources.Mem,
+		GPUS: s.UnReservedResources.GPUS - s.UsedResources.GPUS,
+		CPUS: s.UnReservedResources.CPUS - s.UsedResources.CPUS,
+	}
+}
+
 func FetchAgents(conf *configuration.Configuration) (map[string]Slave, error) {
-	client := &http.Client{}
-	req, _ := http.NewRequest("GET", conf.Mesos.Endpoint+"/slaves", nil)
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	response, err := client.Do(req)
+	response, err := doMaster(conf, "/slaves")
 
 	if err != nil {
 		return nil, err
 	}
 
+	if err := httpclient.CheckStatus(response); err != nil {
+		return nil, err
+	}
+
 	defer response.Body.Close()
 	var slaves slaves
 
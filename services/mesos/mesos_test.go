@@ -41,7 +41,8 @@ func TestFetchAgentStatistics(t *testing.T) {
 
 	slave.PID = "test@" + url
 
-	resources, err := slave.FetchAgentStatistics()
+	conf := &configuration.Configuration{}
+	resources, err := slave.FetchAgentStatistics(conf)
 
 	if err != nil {
 		log.Fatal(err)
@@ -49,6 +50,7 @@ func TestFetchAgentStatistics(t *testing.T) {
 
 	for _, resource := range resources {
 		assert.Equal(t, "aa53014e-04cc-49e7-975d-60c635a70c7f-0001", resource.FrameworkID)
+		assert.False(t, resource.Statistics.HasNetwork)
 	}
 }
 
@@ -158,3 +160,75 @@ func TestFetchAgents(t *testing.T) {
 		assert.Equal(t, "10.20.188.205:5051", url)
 	}
 }
+
+// statistics with the fields of the cgroups, port mapping and disk/du
+// isolators, written by hand from the ResourceStatistics message rather than
+// captured from an agent
+const fullStatisticsJSON = `[{
+	"executor_id": "smartfocus-api-openid.d2060420-b541-11e6-8310-0efb52840a34",
+	"executor_name": "Command Executor (Task: smartfocus-api-openid.d2060420-b541-11e6-8310-0efb52840a34) (Command: NO EXECUTABLE)",
+	"framework_id": "aa53014e-04cc-49e7-975d-60c635a70c7f-0001",
+	"source": "smartfocus-api-openid.d2060420-b541-11e6-8310-0efb52840a34",
+	"statistics": {
+		"cpus_limit": 0.6,
+		"cpus_nr_periods": 2781,
+		"cpus_nr_throttled": 214,
+		"cpus_system_time_secs": 0.78,
+		"cpus_throttled_time_secs": 12.517436443,
+		"cpus_user_time_secs": 4.25,
+		"disk_limit_bytes": 134217728,
+		"disk_used_bytes": 40960,
+		"mem_anon_bytes": 151044096,
+		"mem_cache_bytes": 9416704,
+		"mem_critical_pressure_counter": 0,
+		"mem_file_bytes": 9416704,
+		"mem_limit_bytes": 301989888,
+		"mem_low_pressure_counter": 3,
+		"mem_mapped_file_bytes": 1851392,
+		"mem_medium_pressure_counter": 0,
+		"mem_rss_bytes": 160460800,
+		"mem_swap_bytes": 0,
+		"mem_total_bytes": 169877504,
+		"mem_unevictable_bytes": 0,
+		"net_rx_bytes": 94863841,
+		"net_rx_dropped": 2,
+		"net_rx_errors": 0,
+		"net_rx_packets": 162438,
+		"net_tx_bytes": 218403322,
+		"net_tx_dropped": 0,
+		"net_tx_errors": 0,
+		"net_tx_packets": 181736,
+		"processes": 3,
+		"threads": 41,
+		"timestamp": 1480333639.83199
+	}
+}]`
+
+func TestFetchAgentFullStatistics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, fullStatisticsJSON)
+	}))
+	defer ts.Close()
+
+	slave := Slave{PID: "slave(1)@" + strings.TrimPrefix(ts.URL, "http://")}
+
+	resources, err := slave.FetchAgentStatistics(&configuration.Configuration{})
+	assert.NoError(t, err)
+	assert.Len(t, resources, 1)
+
+	statistics := resources[0].Statistics
+	assert.Equal(t, 2781, statistics.CPUsNrPeriods)
+	assert.Equal(t, 214, statistics.CPUsNrThrottled)
+	assert.Equal(t, 12.517436443, statistics.CPUsThrottledTimeSecs)
+	assert.Equal(t, 169877504, statistics.MemTotalBytes)
+	assert.Equal(t, 9416704, statistics.MemCacheBytes)
+	assert.Equal(t, 94863841, statistics.NetRxBytes)
+	assert.Equal(t, 2, statistics.NetRxDropped)
+	assert.Equal(t, 218403322, statistics.NetTxBytes)
+	assert.Equal(t, 0, statistics.NetTxDropped)
+	assert.Equal(t, 40960, statistics.DiskUsedBytes)
+	assert.Equal(t, 134217728, statistics.DiskLimitBytes)
+	assert.Equal(t, 41, statistics.Threads)
+	assert.True(t, statistics.HasNetwork)
+	assert.Equal(t, 1480333639.83199, statistics.Timestamp)
+}
@@ -0,0 +1,224 @@
+package mesos
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/rossmerr/marathon-autoscale/services/httpclient"
+)
+
+// OperatorClient of the Mesos v1 operator HTTP API, for Mesos versions where
+// the legacy /slaves and /monitor/statistics endpoints are deprecated
+type OperatorClient struct {
+	conf *configuration.Configuration
+}
+
+func NewOperatorClient(conf *configuration.Configuration) *OperatorClient {
+	return &OperatorClient{conf: conf}
+}
+
+type value struct {
+	Value string `json:"value"`
+}
+
+type operatorResource struct {
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+	Scalar struct {
+		Value float64 `json:"value"`
+	} `json:"scalar"`
+	Reservation  *json.RawMessage  `json:"reservation"`
+	Reservations []json.RawMessage `json:"reservations"`
+}
+
+type operatorAgent struct {
+	AgentInfo struct {
+		ID       value  `json:"id"`
+		Hostname string `json:"hostname"`
+	} `json:"agent_info"`
+	Active         bool   `json:"active"`
+	PID            string `json:"pid"`
+	Version        string `json:"version"`
+	RegisteredTime struct {
+		Nanoseconds int64 `json:"nanoseconds"`
+	} `json:"registered_time"`
+	TotalResources     []operatorResource `json:"total_resources"`
+	AllocatedResources []operatorResource `json:"allocated_resources"`
+	OfferedResources   []operatorResource `json:"offered_resources"`
+}
+
+type operatorContainer struct {
+	ContainerID        value      `json:"container_id"`
+	ExecutorID         value      `json:"executor_id"`
+	ExecutorName       string     `json:"executor_name"`
+	FrameworkID        value      `json:"framework_id"`
+	ResourceStatistics Statistics `json:"resource_statistics"`
+}
+
+type operatorTask struct {
+	Name        string `json:"name"`
+	TaskID      value  `json:"task_id"`
+	FrameworkID value  `json:"framework_id"`
+	ExecutorID  value  `json:"executor_id"`
+	AgentID     value  `json:"agent_id"`
+	State       string `json:"state"`
+	Statuses    []struct {
+		ContainerStatus struct {
+			ContainerID value `json:"container_id"`
+		} `json:"container_status"`
+	} `json:"statuses"`
+}
+
+type operatorResponse struct {
+	GetAgents struct {
+		Agents []operatorAgent `json:"agents"`
+	} `json:"get_agents"`
+	GetContainers struct {
+		Containers []operatorContainer `json:"containers"`
+	} `json:"get_containers"`
+	GetTasks struct {
+		Tasks []operatorTask `json:"tasks"`
+	} `json:"get_tasks"`
+}
+
+func call(callType string) []byte {
+	return []byte(`{"type": "` + callType + `"}`)
+}
+
+// decode reads the operator API response into v
+func decode(response *http.Response, v interface{}) error {
+	if err := httpclient.CheckStatus(response); err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(contents, v)
+}
+
+// sum adds up the scalar resources by name
+func sum(resources []operatorResource, include func(operatorResource) bool) SlaveResources {
+	var total SlaveResources
+	for _, resource := range resources {
+		if !include(resource) {
+			continue
+		}
+		switch resource.Name {
+		case "cpus":
+			total.CPUS += float32(resource.Scalar.Value)
+		case "mem":
+			total.Mem += int(resource.Scalar.Value)
+		case "disk":
+			total.Disk += int(resource.Scalar.Value)
+		case "gpus":
+			total.GPUS += float32(resource.Scalar.Value)
+		}
+	}
+	return total
+}
+
+func all(operatorResource) bool {
+	return true
+}
+
+func unreserved(resource operatorResource) bool {
+	return resource.Reservation == nil && len(resource.Reservations) == 0 && (resource.Role == "" || resource.Role == "*")
+}
+
+func (c *OperatorClient) FetchAgents() (map[string]Slave, error) {
+	response, err := doMasterCall(c.conf, "POST", "/api/v1", call("GET_AGENTS"))
+	if err != nil {
+		return nil, err
+	}
+
+	var result operatorResponse
+	if err := decode(response, &result); err != nil {
+		return nil, err
+	}
+
+	slaveByID := map[string]Slave{}
+
+	for _, agent := range result.GetAgents.Agents {
+		slaveByID[agent.AgentInfo.ID.Value] = Slave{
+			ID:                  agent.AgentInfo.ID.Value,
+			PID:                 agent.PID,
+			Hostname:            agent.AgentInfo.Hostname,
+			RegisteredTime:      float32(float64(agent.RegisteredTime.Nanoseconds) / 1e9),
+			Resources:           sum(agent.TotalResources, all),
+			UsedResources:       sum(agent.AllocatedResources, all),
+			OfferedResources:    sum(agent.OfferedResources, all),
+			UnReservedResources: sum(agent.TotalResources, unreserved),
+			Active:              agent.Active,
+			Version:             agent.Version,
+		}
+	}
+
+	return slaveByID, nil
+}
+
+func (c *OperatorClient) FetchAgentStatistics(agent Slave) ([]Resource, error) {
+	response, err := doAgent(c.conf, agent, "POST", "/api/v1", call("GET_CONTAINERS"))
+	if err != nil {
+		return nil, err
+	}
+
+	var result operatorResponse
+	if err := decode(response, &result); err != nil {
+		return nil, err
+	}
+
+	resources := Resources{}
+
+	for _, container := range result.GetContainers.Containers {
+		resources = append(resources, Resource{
+			ExecutorID:   container.ExecutorID.Value,
+			ExecutorName: container.ExecutorName,
+			FrameworkID:  container.FrameworkID.Value,
+			ContainerID:  container.ContainerID.Value,
+			Statistics:   container.ResourceStatistics,
+		})
+	}
+
+	return resources, nil
+}
+
+// FetchTasks returns the tasks known to the master by task ID
+func (c *OperatorClient) FetchTasks() (map[string]Task, error) {
+	response, err := doMasterCall(c.conf, "POST", "/api/v1", call("GET_TASKS"))
+	if err != nil {
+		return nil, err
+	}
+
+	var result operatorResponse
+	if err := decode(response, &result); err != nil {
+		return nil, err
+	}
+
+	taskByID := map[string]Task{}
+
+	for _, task := range result.GetTasks.Tasks {
+		t := Task{
+			ID:          task.TaskID.Value,
+			Name:        task.Name,
+			FrameworkID: task.FrameworkID.Value,
+			ExecutorID:  task.ExecutorID.Value,
+			AgentID:     task.AgentID.Value,
+			State:       task.State,
+		}
+		for _, status := range task.Statuses {
+			if len(status.ContainerStatus.ContainerID.Value) > 0 {
+				t.ContainerID = status.ContainerStatus.ContainerID.Value
+			}
+		}
+		taskByID[t.ID] = t
+	}
+
+	return taskByID, nil
+}
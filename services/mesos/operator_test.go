@@ -0,0 +1,166 @@
+package mesos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/stretchr/testify/assert"
+)
+
+const getAgentsJSON = `{
+  "type": "GET_AGENTS",
+  "get_agents": {
+    "agents": [
+      {
+        "agent_info": {
+          "id": {"value": "aa53014e-04cc-49e7-975d-60c635a70c7f-S29"},
+          "hostname": "10.20.188.205",
+          "port": 5051
+        },
+        "active": true,
+        "pid": "slave(1)@10.20.188.205:5051",
+        "version": "1.4.0",
+        "registered_time": {"nanoseconds": 1480320210800410000},
+        "total_resources": [
+          {"name": "cpus", "type": "SCALAR", "scalar": {"value": 1}, "role": "*"},
+          {"name": "cpus", "type": "SCALAR", "scalar": {"value": 1}, "role": "slave_public", "reservation": {}},
+          {"name": "mem", "type": "SCALAR", "scalar": {"value": 999}, "role": "*"},
+          {"name": "disk", "type": "SCALAR", "scalar": {"value": 4971}, "role": "*"}
+        ],
+        "allocated_resources": [
+          {"name": "cpus", "type": "SCALAR", "scalar": {"value": 0.95}, "role": "*"},
+          {"name": "mem", "type": "SCALAR", "scalar": {"value": 768}, "role": "*"}
+        ]
+      }
+    ]
+  }
+}`
+
+const getContainersJSON = `{
+  "type": "GET_CONTAINERS",
+  "get_containers": {
+    "containers": [
+      {
+        "container_id": {"value": "8f7c7a35-2e4b-4a3c-9a0b-4bb2e8b1b6a1"},
+        "executor_id": {"value": "smartfocus-api-openid.d2060420-b541-11e6-8310-0efb52840a34"},
+        "executor_name": "Command Executor",
+        "framework_id": {"value": "aa53014e-04cc-49e7-975d-60c635a70c7f-0001"},
+        "resource_statistics": {
+          "cpus_limit": 0.6,
+          "cpus_nr_periods": 2781,
+          "cpus_nr_throttled": 214,
+          "cpus_throttled_time_secs": 12.517436443,
+          "cpus_system_time_secs": 0.78,
+          "cpus_user_time_secs": 4.25,
+          "mem_limit_bytes": 301989888,
+          "mem_rss_bytes": 160460800,
+          "mem_total_bytes": 169877504,
+          "mem_cache_bytes": 9416704,
+          "net_rx_bytes": 94863841,
+          "net_tx_bytes": 218403322,
+          "timestamp": 1480333639.83199
+        }
+      }
+    ]
+  }
+}`
+
+const getTasksJSON = `{
+  "type": "GET_TASKS",
+  "get_tasks": {
+    "tasks": [
+      {
+        "name": "smartfocus-api-openid",
+        "task_id": {"value": "smartfocus-api-openid.d2060420-b541-11e6-8310-0efb52840a34"},
+        "framework_id": {"value": "aa53014e-04cc-49e7-975d-60c635a70c7f-0001"},
+        "agent_id": {"value": "aa53014e-04cc-49e7-975d-60c635a70c7f-S29"},
+        "state": "TASK_RUNNING",
+        "statuses": [
+          {"state": "TASK_RUNNING", "container_status": {"container_id": {"value": "8f7c7a35-2e4b-4a3c-9a0b-4bb2e8b1b6a1"}}}
+        ]
+      }
+    ]
+  }
+}`
+
+// operatorServer answers the operator API calls with the given responses by call type
+func operatorServer(t *testing.T, responses map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, "POST", r.Method)
+
+		body, _ := ioutil.ReadAll(r.Body)
+		for callType, response := range responses {
+			if strings.Contains(string(body), `"`+callType+`"`) {
+				fmt.Fprintln(w, response)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+}
+
+func TestOperatorFetchAgents(t *testing.T) {
+	ts := operatorServer(t, map[string]string{"GET_AGENTS": getAgentsJSON})
+	defer ts.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Mesos.Endpoint = ts.URL
+
+	agents, err := NewOperatorClient(conf).FetchAgents()
+	assert.NoError(t, err)
+
+	agent := agents["aa53014e-04cc-49e7-975d-60c635a70c7f-S29"]
+	assert.Equal(t, "10.20.188.205", agent.Hostname)
+	assert.Equal(t, float32(2), agent.Resources.CPUS)
+	assert.Equal(t, float32(1), agent.UnReservedResources.CPUS)
+	assert.Equal(t, 999, agent.Resources.Mem)
+	assert.Equal(t, 768, agent.UsedResources.Mem)
+	assert.True(t, agent.Active)
+
+	endpoint, _ := agent.Endpoint()
+	assert.Equal(t, "10.20.188.205:5051", endpoint)
+}
+
+func TestOperatorFetchAgentStatistics(t *testing.T) {
+	ts := operatorServer(t, map[string]string{"GET_CONTAINERS": getContainersJSON})
+	defer ts.Close()
+
+	agent := Slave{PID: "slave(1)@" + strings.TrimPrefix(ts.URL, "http://")}
+
+	resources, err := NewOperatorClient(&configuration.Configuration{}).FetchAgentStatistics(agent)
+	assert.NoError(t, err)
+
+	assert.Len(t, resources, 1)
+	assert.Equal(t, "smartfocus-api-openid.d2060420-b541-11e6-8310-0efb52840a34", resources[0].ExecutorID)
+	assert.Equal(t, "aa53014e-04cc-49e7-975d-60c635a70c7f-0001", resources[0].FrameworkID)
+	assert.Equal(t, "8f7c7a35-2e4b-4a3c-9a0b-4bb2e8b1b6a1", resources[0].ContainerID)
+	assert.Equal(t, 4.25, resources[0].Statistics.CPUsUserTimeSecs)
+	assert.Equal(t, 214, resources[0].Statistics.CPUsNrThrottled)
+	assert.Equal(t, 9416704, resources[0].Statistics.MemCacheBytes)
+	assert.Equal(t, 218403322, resources[0].Statistics.NetTxBytes)
+}
+
+func TestOperatorFetchTasks(t *testing.T) {
+	ts := operatorServer(t, map[string]string{"GET_TASKS": getTasksJSON})
+	defer ts.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Mesos.Endpoint = ts.URL
+
+	tasks, err := NewOperatorClient(conf).FetchTasks()
+	assert.NoError(t, err)
+
+	task := tasks["smartfocus-api-openid.d2060420-b541-11e6-8310-0efb52840a34"]
+	assert.Equal(t, "aa53014e-04cc-49e7-975d-60c635a70c7f-S29", task.AgentID)
+	assert.Equal(t, "TASK_RUNNING", task.State)
+	assert.Equal(t, "8f7c7a35-2e4b-4a3c-9a0b-4bb2e8b1b6a1", task.ContainerID)
+}
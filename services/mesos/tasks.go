@@ -0,0 +1,91 @@
+package mesos
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/rossmerr/marathon-autoscale/services/httpclient"
+)
+
+// tasksPageSize of the master /tasks endpoint, which returns 100 tasks by default
+const tasksPageSize = 1000
+
+// Task as reported by the Mesos master
+type Task struct {
+	ID          string
+	Name        string
+	FrameworkID string
+	ExecutorID  string
+	AgentID     string
+	ContainerID string
+	State       string
+}
+
+type masterTask struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	FrameworkID string `json:"framework_id"`
+	ExecutorID  string `json:"executor_id"`
+	SlaveID     string `json:"slave_id"`
+	State       string `json:"state"`
+	Statuses    []struct {
+		ContainerStatus struct {
+			ContainerID value `json:"container_id"`
+		} `json:"container_status"`
+	} `json:"statuses"`
+}
+
+type masterTasks struct {
+	Tasks []masterTask `json:"tasks"`
+}
+
+// FetchTasks returns the tasks known to the master by task ID, reading the
+// /tasks endpoint page by page
+func FetchTasks(conf *configuration.Configuration) (map[string]Task, error) {
+	taskByID := map[string]Task{}
+
+	for offset := 0; ; offset += tasksPageSize {
+		response, err := doMaster(conf, "/tasks?limit="+strconv.Itoa(tasksPageSize)+"&offset="+strconv.Itoa(offset))
+		if err != nil {
+			return nil, err
+		}
+
+		if err := httpclient.CheckStatus(response); err != nil {
+			return nil, err
+		}
+
+		contents, err := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		var page masterTasks
+		if err := json.Unmarshal(contents, &page); err != nil {
+			return nil, err
+		}
+
+		for _, task := range page.Tasks {
+			t := Task{
+				ID:          task.ID,
+				Name:        task.Name,
+				FrameworkID: task.FrameworkID,
+				ExecutorID:  task.ExecutorID,
+				AgentID:     task.SlaveID,
+				State:       task.State,
+			}
+			for _, status := range task.Statuses {
+				if len(status.ContainerStatus.ContainerID.Value) > 0 {
+					t.ContainerID = status.ContainerStatus.ContainerID.Value
+				}
+			}
+			taskByID[t.ID] = t
+		}
+
+		if len(page.Tasks) < tasksPageSize {
+			return taskByID, nil
+		}
+	}
+}
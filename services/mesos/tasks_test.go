@@ -0,0 +1,52 @@
+package mesos
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/stretchr/testify/assert"
+)
+
+const tasksJSON = `{
+  "tasks": [
+    {
+      "id": "smartfocus-api-openid.d2060420-b541-11e6-8310-0efb52840a34",
+      "name": "smartfocus-api-openid",
+      "framework_id": "aa53014e-04cc-49e7-975d-60c635a70c7f-0001",
+      "executor_id": "",
+      "slave_id": "aa53014e-04cc-49e7-975d-60c635a70c7f-S29",
+      "state": "TASK_RUNNING",
+      "statuses": [
+        {"state": "TASK_RUNNING", "container_status": {"container_id": {"value": "8f7c7a35-2e4b-4a3c-9a0b-4bb2e8b1b6a1"}}}
+      ]
+    }
+  ]
+}`
+
+func TestFetchTasks(t *testing.T) {
+	offsets := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tasks" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		offsets = append(offsets, r.URL.Query().Get("offset"))
+		fmt.Fprintln(w, tasksJSON)
+	}))
+	defer ts.Close()
+
+	conf := &configuration.Configuration{}
+	conf.Mesos.Endpoint = ts.URL
+
+	tasks, err := NewClient(conf).FetchTasks()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0"}, offsets)
+
+	task := tasks["smartfocus-api-openid.d2060420-b541-11e6-8310-0efb52840a34"]
+	assert.Equal(t, "aa53014e-04cc-49e7-975d-60c635a70c7f-0001", task.FrameworkID)
+	assert.Equal(t, "aa53014e-04cc-49e7-975d-60c635a70c7f-S29", task.AgentID)
+	assert.Equal(t, "8f7c7a35-2e4b-4a3c-9a0b-4bb2e8b1b6a1", task.ContainerID)
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParseJSON reads a JSON document and returns the number at the dotted field
+// path, array elements being addressed by index, e.g. gauges.requests.value
+// or backends.0.active
+func ParseJSON(r io.Reader, path string) (float64, error) {
+	var document interface{}
+	if err := json.NewDecoder(r).Decode(&document); err != nil {
+		return 0, err
+	}
+
+	value := document
+	for _, field := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			child, ok := node[field]
+			if !ok {
+				return 0, ErrMetricNotFound
+			}
+			value = child
+		case []interface{}:
+			index, err := strconv.Atoi(field)
+			if err != nil || index < 0 || index >= len(node) {
+				return 0, ErrMetricNotFound
+			}
+			value = node[index]
+		default:
+			return 0, ErrMetricNotFound
+		}
+	}
+
+	switch number := value.(type) {
+	case float64:
+		return number, nil
+	case string:
+		return strconv.ParseFloat(number, 64)
+	default:
+		return 0, errors.New("Metric " + path + " is not a number")
+	}
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/rossmerr/marathon-autoscale/services/httpclient"
+)
+
+// ErrMetricNotFound is returned when the scraped response has no such metric
+var ErrMetricNotFound = errors.New("Metric not found")
+
+// Source of an application metric served by each of its tasks
+type Source struct {
+	// HTTP path of the metrics endpoint, e.g. /metrics
+	Path string
+	// index of the task port serving the endpoint
+	PortIndex int
+	// prometheus for the text exposition format, or json
+	Format string
+	// metric name for prometheus, optionally with labels to match, e.g.
+	// http_requests_in_flight{handler="api"}, or a dotted field path for
+	// json, e.g. gauges.requests.value
+	Name string
+}
+
+// Enabled is true when the source names an endpoint and a metric
+func (s Source) Enabled() bool {
+	return len(s.Path) > 0 && len(s.Name) > 0
+}
+
+// URL of the metrics endpoint of a task running on host with the given ports
+func (s Source) URL(host string, ports []int) (string, error) {
+	if s.PortIndex < 0 || s.PortIndex >= len(ports) {
+		return "", errors.New("Task has no port index " + strconv.Itoa(s.PortIndex))
+	}
+
+	return "http://" + host + ":" + strconv.Itoa(ports[s.PortIndex]) + s.Path, nil
+}
+
+// Scrape fetches the metric from a task's endpoint, giving up when ctx is done
+func Scrape(ctx context.Context, conf *configuration.Configuration, source Source, host string, ports []int) (float64, error) {
+	url, err := source.URL(host, ports)
+	if err != nil {
+		return 0, err
+	}
+
+	client, err := httpclient.NewWithTimeout(configuration.TLS{}, conf.HTTP)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+
+	if source.Format == "json" {
+		req.Header.Add("Accept", "application/json")
+	} else {
+		req.Header.Add("Accept", "text/plain")
+	}
+
+	response, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := httpclient.CheckStatus(response); err != nil {
+		return 0, err
+	}
+
+	defer response.Body.Close()
+
+	return Parse(response.Body, source.Format, source.Name)
+}
+
+// Parse reads the metric from a response body in the given format
+func Parse(r io.Reader, format string, name string) (float64, error) {
+	switch format {
+	case "prometheus", "":
+		return ParsePrometheus(r, name)
+	case "json":
+		return ParseJSON(r, name)
+	default:
+		return 0, errors.New("Unknown metric format " + format)
+	}
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rossmerr/marathon-autoscale/configuration"
+	"github.com/stretchr/testify/assert"
+)
+
+const prometheusText = `# HELP http_requests_in_flight Requests currently being served.
+# TYPE http_requests_in_flight gauge
+http_requests_in_flight{handler="api",method="GET"} 7
+http_requests_in_flight{handler="api",method="POST"} 3
+http_requests_in_flight{handler="health\"z"} 1 1480333639831
+process_open_fds 42
+`
+
+const metricsJSON = `{
+  "gauges": {"requests": {"value": 12.5}},
+  "backends": [{"active": "4"}, {"active": 9}]
+}`
+
+func TestParsePrometheus(t *testing.T) {
+	value, err := ParsePrometheus(strings.NewReader(prometheusText), "http_requests_in_flight")
+	assert.NoError(t, err)
+	assert.Equal(t, 11.0, value)
+
+	value, err = ParsePrometheus(strings.NewReader(prometheusText), `http_requests_in_flight{handler="api"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, 10.0, value)
+
+	value, err = ParsePrometheus(strings.NewReader(prometheusText), `http_requests_in_flight{handler="health\"z"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, value)
+
+	_, err = ParsePrometheus(strings.NewReader(prometheusText), "http_requests_total")
+	assert.Equal(t, ErrMetricNotFound, err)
+}
+
+func TestParseJSON(t *testing.T) {
+	value, err := ParseJSON(strings.NewReader(metricsJSON), "gauges.requests.value")
+	assert.NoError(t, err)
+	assert.Equal(t, 12.5, value)
+
+	value, err = ParseJSON(strings.NewReader(metricsJSON), "backends.0.active")
+	assert.NoError(t, err)
+	assert.Equal(t, 4.0, value)
+
+	_, err = ParseJSON(strings.NewReader(metricsJSON), "backends.2.active")
+	assert.Equal(t, ErrMetricNotFound, err)
+
+	_, err = ParseJSON(strings.NewReader(metricsJSON), "gauges.requests")
+	assert.Error(t, err)
+}
+
+func TestScrape(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/metrics", r.URL.Path)
+		fmt.Fprint(w, prometheusText)
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	source := Source{Path: "/metrics", PortIndex: 1, Name: "process_open_fds"}
+	value, err := Scrape(context.Background(), &configuration.Configuration{}, source, u.Hostname(), []int{1, port})
+	assert.NoError(t, err)
+	assert.Equal(t, 42.0, value)
+
+	source.PortIndex = 2
+	_, err = Scrape(context.Background(), &configuration.Configuration{}, source, u.Hostname(), []int{1, port})
+	assert.Error(t, err)
+}
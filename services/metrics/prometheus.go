@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParsePrometheus reads the Prometheus text exposition format and returns the
+// sum of the series of the named metric carrying the labels of the selector,
+// e.g. http_requests_in_flight or http_requests_in_flight{handler="api"}
+func ParsePrometheus(r io.Reader, selector string) (float64, error) {
+	name, want, err := parseSelector(selector)
+	if err != nil {
+		return 0, err
+	}
+
+	found := false
+	total := 0.0
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		sampleName, labels, value, err := parseSample(line)
+		if err != nil || sampleName != name || !hasLabels(labels, want) {
+			continue
+		}
+
+		found = true
+		total += value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	if !found {
+		return 0, ErrMetricNotFound
+	}
+
+	return total, nil
+}
+
+// parseSelector splits a selector into the metric name and the labels to match
+func parseSelector(selector string) (string, map[string]string, error) {
+	index := strings.IndexByte(selector, '{')
+	if index == -1 {
+		return strings.TrimSpace(selector), nil, nil
+	}
+
+	labels, _, err := parseLabels(selector[index:])
+	return strings.TrimSpace(selector[:index]), labels, err
+}
+
+// parseSample parses a line like name{label="value"} 1.5 [timestamp]
+func parseSample(line string) (string, map[string]string, float64, error) {
+	index := strings.IndexAny(line, "{ \t")
+	if index == -1 {
+		return "", nil, 0, errors.New("Sample has no value: " + line)
+	}
+
+	name := line[:index]
+	rest := line[index:]
+
+	var labels map[string]string
+	if rest[0] == '{' {
+		var err error
+		if labels, rest, err = parseLabels(rest); err != nil {
+			return "", nil, 0, err
+		}
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", nil, 0, errors.New("Sample has no value: " + line)
+	}
+
+	value, err := strconv.ParseFloat(fields[0], 64)
+	return name, labels, value, err
+}
+
+// parseLabels parses a label set starting at the opening brace of s and
+// returns what follows the closing brace
+func parseLabels(s string) (map[string]string, string, error) {
+	labels := map[string]string{}
+	i := 1
+
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == ',') {
+			i++
+		}
+		if i >= len(s) {
+			return nil, "", errors.New("Unterminated label set: " + s)
+		}
+		if s[i] == '}' {
+			return labels, s[i+1:], nil
+		}
+
+		eq := strings.IndexByte(s[i:], '=')
+		if eq == -1 {
+			return nil, "", errors.New("Label without value: " + s)
+		}
+		key := strings.TrimSpace(s[i : i+eq])
+		i += eq + 1
+
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+		if i >= len(s) || s[i] != '"' {
+			return nil, "", errors.New("Unquoted label value: " + s)
+		}
+		i++
+
+		var value strings.Builder
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				if s[i] == 'n' {
+					value.WriteByte('\n')
+				} else {
+					value.WriteByte(s[i])
+				}
+				continue
+			}
+			value.WriteByte(s[i])
+		}
+		if i >= len(s) {
+			return nil, "", errors.New("Unterminated label value: " + s)
+		}
+		i++
+
+		labels[key] = value.String()
+	}
+}
+
+func hasLabels(labels map[string]string, want map[string]string) bool {
+	for key, value := range want {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}